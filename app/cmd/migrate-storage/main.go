@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/vharitonsky/iniflags"
+	"os"
+)
+
+func main() {
+	iniflags.Parse()
+
+	if err := service.MigrateStorageLayout(); err != nil {
+		fmt.Printf("Error migrating storage layout: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Folder delete policies: restrict refuses to delete a folder that is not empty, move_to_parent hands
+// its subfolders and videos to its parent (or the root) and delete_contents deletes the whole subtree
+// with its videos.
+const (
+	FolderDeleteRestrict       = "restrict"
+	FolderDeleteMoveToParent   = "move_to_parent"
+	FolderDeleteDeleteContents = "delete_contents"
+)
+
+var FolderDeletePolicies = []string{FolderDeleteRestrict, FolderDeleteMoveToParent, FolderDeleteDeleteContents}
+
+type Folder struct {
+	Id         int       `json:"id"`
+	ParentId   *int      `json:"parent_id"`
+	Name       string    `json:"name"`
+	VideoCount int       `json:"video_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UserId     int       `json:"-"`
+}
+
+// FolderReq creates a folder or, in a PATCH, renames and moves it. A parent_id of null moves it to the root.
+type FolderReq struct {
+	Name     *string    `json:"name"`
+	ParentId OptionalID `json:"parent_id"`
+}
+
+// OptionalID tells an absent id apart from an explicit null.
+type OptionalID struct {
+	Set bool
+	Id  *int
+}
+
+func (o *OptionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Id)
+}
+
+type FolderVideosReq struct {
+	VideoIds []int `json:"video_ids"`
+}
+
+type FolderDeleteResp struct {
+	DeletedFolders []int `json:"deleted_folders"`
+	DeletedVideos  []int `json:"deleted_videos"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+const (
+	SortCreated  = "created"
+	SortUpdated  = "updated"
+	SortName     = "name"
+	SortSize     = "size"
+	SortDuration = "duration"
+)
+
+var VideoSorts = []string{SortCreated, SortUpdated, SortName, SortSize, SortDuration}
+
+// VideoFilter selects the files of a listing; zero values match everything.
+type VideoFilter struct {
+	VideoId     int
+	Statuses    []string
+	IsStream    *bool
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	NamePrefix  string
+	// FolderIds limits the listing to videos in these folders, Unfiled to videos in no folder.
+	FolderIds []int
+	Unfiled   bool
+}
+
+type VideoListReq struct {
+	VideoFilter
+	// FolderId lists one folder, including its subfolders with Recursive.
+	FolderId  int
+	Recursive bool
+	Sort      string
+	Desc      bool
+	Limit     int
+	Cursor    string
+}
+
+// VideoCursor is the position after the last item of a page: its sort value and id as a tie breaker.
+// Value holds milliseconds for the time sorts, the file name for name and an integer otherwise.
+type VideoCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value any    `json:"v"`
+	Id    int    `json:"id"`
+}
+
+type VideoListResp struct {
+	Items      []*InfoVideosResp `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	Total      int               `json:"total"`
+}
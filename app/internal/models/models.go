@@ -1,17 +1,60 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type StatusErrorResp struct {
 	Id       int    `json:"id"`
 	FileName string `json:"file_name"`
 }
 
 type InfoVideosResp struct {
-	Id       int    `json:"id"`
-	FileName string `json:"file_name"`
-	Status   string `json:"status"`
-	IsStream bool   `json:"is_stream"`
-	FilePath string `json:"file_path,omitempty"`
-	StatusAI string `json:"status_ai,omitempty"`
+	Id        int            `json:"id"`
+	FileName  string         `json:"file_name"`
+	Status    string         `json:"status"`
+	IsStream  bool           `json:"is_stream"`
+	FilePath  string         `json:"file_path,omitempty"`
+	StatusAI  string         `json:"status_ai,omitempty"`
+	Container string         `json:"container,omitempty"`
+	Metadata  *VideoMetadata `json:"metadata,omitempty"`
+
+	LastError          string `json:"last_error,omitempty"`
+	ConversionAttempts int    `json:"conversion_attempts"`
+	RetryCount         int    `json:"retry_count"`
+
+	Size      int64     `json:"size,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Title        string          `json:"title,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	Tags         []string        `json:"tags"`
+	Language     string          `json:"language,omitempty"`
+	CustomFields json.RawMessage `json:"custom_fields,omitempty"`
+	FolderId     *int            `json:"folder_id"`
+}
+
+// VideoDetailsReq is a partial update of the user-editable fields: absent fields are left unchanged,
+// empty strings and a null custom_fields clear them and custom_fields replaces the stored object.
+type VideoDetailsReq struct {
+	Title        *string         `json:"title"`
+	Description  *string         `json:"description"`
+	Tags         *[]string       `json:"tags"`
+	Language     *string         `json:"language"`
+	CustomFields json.RawMessage `json:"custom_fields"`
+}
+
+type VideoMetadata struct {
+	DurationMs int64   `json:"duration_ms"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	FrameRate  float64 `json:"frame_rate"`
+	VideoCodec string  `json:"video_codec,omitempty"`
+	AudioCodec string  `json:"audio_codec,omitempty"`
+	Bitrate    int64   `json:"bitrate"`
+	Rotation   int     `json:"rotation"`
 }
 
 type VideoFormatLinksResp struct {
@@ -21,8 +64,87 @@ type VideoFormatLinksResp struct {
 	Formats       []VideoFormat `json:"formats"`
 }
 type VideoFormat struct {
-	URL        string `json:"url"`
-	Resolution string `json:"size"`
+	URL        string         `json:"url"`
+	Resolution string         `json:"size"`
+	Bitrate    int64          `json:"bitrate,omitempty"`
+	Codec      string         `json:"codec,omitempty"`
+	InitURL    string         `json:"init_url,omitempty"`
+	Segments   []VideoSegment `json:"segments,omitempty"`
+}
+
+type VideoSegment struct {
+	URL      string  `json:"url"`
+	Duration float64 `json:"duration"`
+}
+
+type RenditionReq struct {
+	URL        string                `json:"url"`
+	Path       string                `json:"path"`
+	Resolution string                `json:"size"`
+	Bitrate    int64                 `json:"bitrate"`
+	Codec      string                `json:"codec"`
+	Init       *RenditionFileReq     `json:"init"`
+	Segments   []RenditionSegmentReq `json:"segments"`
+}
+
+// RenditionFileReq locates a file by public URL or storage key, like a rendition.
+type RenditionFileReq struct {
+	URL  string `json:"url"`
+	Path string `json:"path"`
+}
+
+type RenditionSegmentReq struct {
+	URL      string  `json:"url"`
+	Path     string  `json:"path"`
+	Duration float64 `json:"duration"`
+}
+
+type RegisterRenditionsReq struct {
+	Renditions []RenditionReq `json:"renditions"`
+}
+
+type JobStatus string
+
+const (
+	JobQueued JobStatus = "queued"
+	JobLeased JobStatus = "leased"
+	JobDone   JobStatus = "done"
+	JobFailed JobStatus = "failed"
+)
+
+type ConversionJob struct {
+	Id             int64     `json:"id"`
+	FileId         int       `json:"file_id"`
+	FilePath       string    `json:"file_path"`
+	SourceURL      string    `json:"source_url"`
+	IsStream       bool      `json:"is_stream"`
+	WorkerId       string    `json:"worker_id,omitempty"`
+	Attempts       int       `json:"attempts"`
+	MaxAttempts    int       `json:"max_attempts"`
+	LeaseToken     string    `json:"lease_token"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+}
+
+type RetryResp struct {
+	FileId      int       `json:"file_id"`
+	RetryCount  int       `json:"retry_count"`
+	AvailableAt time.Time `json:"available_at"`
+}
+
+type JobClaimReq struct {
+	WorkerID     string `json:"worker_id"`
+	LeaseSeconds int    `json:"lease_seconds"`
+}
+
+type JobLeaseReq struct {
+	LeaseToken   string `json:"lease_token"`
+	LeaseSeconds int    `json:"lease_seconds"`
+}
+
+type JobFailReq struct {
+	LeaseToken string `json:"lease_token"`
+	Error      string `json:"error"`
+	Retryable  *bool  `json:"retryable"`
 }
 
 type FileStatus string
@@ -37,3 +159,37 @@ const (
 	StatusLoading   FileStatus = "loading"
 	StatusLoadError FileStatus = "loading_error"
 )
+
+type Upload struct {
+	Id        string
+	UserId    int
+	FileName  string
+	FilePath  string
+	IsStream  bool
+	Length    int64
+	Offset    int64
+	FileId    int
+	UpdatedAt time.Time
+}
+
+type UploadResultStatus string
+
+const (
+	UploadAccepted  UploadResultStatus = "accepted"
+	UploadSkipped   UploadResultStatus = "skipped"
+	UploadDuplicate UploadResultStatus = "duplicate"
+	UploadFailed    UploadResultStatus = "failed"
+)
+
+type UploadFileResult struct {
+	Id       int                `json:"id,omitempty"`
+	FileName string             `json:"file_name"`
+	Status   UploadResultStatus `json:"status"`
+	Reason   string             `json:"reason,omitempty"`
+}
+
+type StoredFile struct {
+	Id       int
+	UserId   int
+	FilePath string
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	OutboxFileCreated          = "file.created"
+	OutboxFileStatusChanged    = "file.status_changed"
+	OutboxFileUpdated          = "file.updated"
+	OutboxRenditionsRegistered = "file.renditions_registered"
+)
+
+type OutboxEvent struct {
+	Id            int64
+	AggregateType string
+	AggregateId   int
+	EventType     string
+	Payload       string
+	Attempts      int
+}
+
+// FileEvent is the payload of outbox events about a file.
+type FileEvent struct {
+	FileId     int           `json:"file_id"`
+	UserId     int           `json:"user_id"`
+	Status     FileStatus    `json:"status"`
+	OldStatus  FileStatus    `json:"old_status,omitempty"`
+	Actor      string        `json:"actor,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	FileName   string        `json:"file_name,omitempty"`
+	FilePath   string        `json:"file_path,omitempty"`
+	IsStream   bool          `json:"is_stream"`
+	Formats    []VideoFormat `json:"formats,omitempty"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
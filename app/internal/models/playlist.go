@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Playlist visibilities: private playlists are only seen by their owner, unlisted ones by anyone with the
+// share token and public ones also by id.
+const (
+	PlaylistPrivate  = "private"
+	PlaylistUnlisted = "unlisted"
+	PlaylistPublic   = "public"
+)
+
+var PlaylistVisibilities = []string{PlaylistPrivate, PlaylistUnlisted, PlaylistPublic}
+
+type Playlist struct {
+	Id          int             `json:"id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Visibility  string          `json:"visibility"`
+	ShareToken  string          `json:"share_token,omitempty"`
+	ItemCount   int             `json:"item_count"`
+	Items       []*PlaylistItem `json:"items,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	UserId      int             `json:"-"`
+}
+
+type PlaylistItem struct {
+	Id       int64  `json:"id"`
+	FileId   int    `json:"file_id"`
+	Position int    `json:"position"`
+	FileName string `json:"file_name"`
+	Title    string `json:"title,omitempty"`
+	Status   string `json:"status"`
+}
+
+type PlaylistReq struct {
+	Title            *string `json:"title"`
+	Description      *string `json:"description"`
+	Visibility       *string `json:"visibility"`
+	RotateShareToken bool    `json:"rotate_share_token"`
+}
+
+// PlaylistItemsReq adds videos at Position, shifting later items, or at the end when it is absent.
+type PlaylistItemsReq struct {
+	VideoIds []int `json:"video_ids"`
+	Position *int  `json:"position"`
+}
+
+// PlaylistOrderReq lists every item id of the playlist in the new order.
+type PlaylistOrderReq struct {
+	ItemIds []int64 `json:"item_ids"`
+}
+
+// PlaylistFeed is a playlist as played back: its ready items in order with their rendition links.
+type PlaylistFeed struct {
+	Id          int                 `json:"id"`
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	Items       []*PlaylistFeedItem `json:"items"`
+}
+
+type PlaylistFeedItem struct {
+	Position int                   `json:"position"`
+	FileId   int                   `json:"file_id"`
+	Title    string                `json:"title"`
+	Links    *VideoFormatLinksResp `json:"links"`
+}
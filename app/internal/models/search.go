@@ -0,0 +1,30 @@
+package models
+
+// SearchDocument is what the search index holds for a file.
+type SearchDocument struct {
+	FileId      int
+	UserId      int
+	FileName    string
+	Title       string
+	Description string
+	Tags        []string
+	Transcript  string
+}
+
+type SearchHit struct {
+	Id         int                 `json:"id"`
+	FileName   string              `json:"file_name"`
+	Title      string              `json:"title,omitempty"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights"`
+}
+
+type SearchResp struct {
+	Items []*SearchHit `json:"items"`
+	Total int          `json:"total"`
+}
+
+type TranscriptReq struct {
+	Language string `json:"language"`
+	Text     string `json:"text"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+var FileStatuses = []FileStatus{
+	StatusLoading, StatusLoadError, StatusNoConv, StatusConv, StatusProcess, StatusError, StatusDone, StatusDeleted,
+}
+
+// fileStatusTransitions lists the statuses a file may move to from each status.
+// Every status except deleted may be deleted.
+var fileStatusTransitions = map[FileStatus][]FileStatus{
+	StatusLoading:   {StatusNoConv, StatusLoadError},
+	StatusLoadError: {},
+	StatusNoConv:    {StatusConv},
+	StatusConv:      {StatusProcess},
+	StatusProcess:   {StatusDone, StatusError, StatusConv},
+	StatusError:     {StatusConv},
+	StatusDone:      {},
+	StatusDeleted:   {},
+}
+
+func (s FileStatus) CanTransitionTo(next FileStatus) bool {
+	if next == StatusDeleted {
+		return s != StatusDeleted
+	}
+	for _, allowed := range fileStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusesBefore returns every status a file may move to next from, used to build conditional updates.
+func StatusesBefore(next FileStatus) []FileStatus {
+	var statuses []FileStatus
+	for _, status := range FileStatuses {
+		if status.CanTransitionTo(next) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+const (
+	ActorSystem    = "system"
+	ActorConverter = "converter"
+)
+
+// StatusChange describes who moved a file to a new status and why; it is stored in the status history.
+type StatusChange struct {
+	Actor  string
+	Reason string
+}
+
+type StatusHistoryEntry struct {
+	Id        int64      `json:"id"`
+	FileId    int        `json:"file_id,omitempty"`
+	OldStatus FileStatus `json:"old_status"`
+	NewStatus FileStatus `json:"new_status"`
+	Actor     string     `json:"actor"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func UserActor(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func WorkerActor(workerID string) string {
+	return "worker:" + workerID
+}
+
+// VideoEvent is one entry of the per-user event stream; its id is the status history id.
+type VideoEvent struct {
+	Id   int64
+	Name string
+	Data *VideoEventData
+}
+
+type VideoEventData struct {
+	FileId    int           `json:"file_id"`
+	Status    FileStatus    `json:"status"`
+	OldStatus FileStatus    `json:"old_status,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Formats   []VideoFormat `json:"formats,omitempty"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from FileStatus
+		to   FileStatus
+		want bool
+	}{
+		{StatusLoading, StatusNoConv, true},
+		{StatusLoading, StatusLoadError, true},
+		{StatusNoConv, StatusConv, true},
+		{StatusConv, StatusProcess, true},
+		{StatusProcess, StatusDone, true},
+		{StatusProcess, StatusError, true},
+		{StatusProcess, StatusConv, true},
+		{StatusError, StatusConv, true},
+		{StatusLoading, StatusDeleted, true},
+		{StatusLoadError, StatusDeleted, true},
+		{StatusNoConv, StatusDeleted, true},
+		{StatusConv, StatusDeleted, true},
+		{StatusProcess, StatusDeleted, true},
+		{StatusError, StatusDeleted, true},
+		{StatusDone, StatusDeleted, true},
+
+		{StatusDone, StatusConv, false},
+		{StatusDone, StatusProcess, false},
+		{StatusLoadError, StatusNoConv, false},
+		{StatusLoading, StatusConv, false},
+		{StatusNoConv, StatusProcess, false},
+		{StatusConv, StatusDone, false},
+		{StatusError, StatusDone, false},
+		{StatusConv, StatusConv, false},
+		{StatusDeleted, StatusLoading, false},
+		{StatusDeleted, StatusLoadError, false},
+		{StatusDeleted, StatusNoConv, false},
+		{StatusDeleted, StatusConv, false},
+		{StatusDeleted, StatusProcess, false},
+		{StatusDeleted, StatusError, false},
+		{StatusDeleted, StatusDone, false},
+		{StatusDeleted, StatusDeleted, false},
+		{StatusDone, "unknown", false},
+		{"unknown", StatusConv, false},
+	}
+
+	allowed := 0
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+		if tt.want {
+			allowed++
+		}
+	}
+
+	// Every allowed edge is listed above, so any other pair that is allowed is a new edge without a test.
+	total := 0
+	for _, from := range FileStatuses {
+		for _, to := range FileStatuses {
+			if from.CanTransitionTo(to) {
+				total++
+			}
+		}
+	}
+	if total != allowed {
+		t.Errorf("%d transitions are allowed, the table lists %d", total, allowed)
+	}
+}
+
+func TestStatusesBefore(t *testing.T) {
+	tests := []struct {
+		next FileStatus
+		want []FileStatus
+	}{
+		{StatusConv, []FileStatus{StatusNoConv, StatusProcess, StatusError}},
+		{StatusDone, []FileStatus{StatusProcess}},
+		{StatusLoading, nil},
+		{StatusDeleted, []FileStatus{StatusLoading, StatusLoadError, StatusNoConv, StatusConv, StatusProcess, StatusError, StatusDone}},
+	}
+
+	for _, tt := range tests {
+		if got := StatusesBefore(tt.next); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("StatusesBefore(%s) = %v, want %v", tt.next, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+const (
+	EventUploaded          = "video.uploaded"
+	EventConversionStarted = "video.conversion_started"
+	EventDone              = "video.done"
+	EventError             = "video.error"
+	EventDeleted           = "video.deleted"
+)
+
+var WebhookEvents = []string{EventUploaded, EventConversionStarted, EventDone, EventError, EventDeleted}
+
+// EventForStatus maps a status a file enters to the lifecycle event announced for it.
+func EventForStatus(status FileStatus) (string, bool) {
+	switch status {
+	case StatusNoConv:
+		return EventUploaded, true
+	case StatusProcess:
+		return EventConversionStarted, true
+	case StatusDone:
+		return EventDone, true
+	case StatusError:
+		return EventError, true
+	case StatusDeleted:
+		return EventDeleted, true
+	default:
+		return "", false
+	}
+}
+
+type Webhook struct {
+	Id        int       `json:"id"`
+	UserId    int       `json:"-"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookReq struct {
+	URL          *string  `json:"url"`
+	Events       []string `json:"events"`
+	Active       *bool    `json:"active"`
+	RotateSecret bool     `json:"rotate_secret"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+type WebhookDelivery struct {
+	Id             int64                 `json:"id"`
+	WebhookId      int                   `json:"webhook_id"`
+	Event          string                `json:"event"`
+	FileId         int                   `json:"file_id"`
+	Payload        string                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookPayload is the JSON body posted to webhook endpoints.
+type WebhookPayload struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       struct {
+		FileId    int        `json:"file_id"`
+		Status    FileStatus `json:"status"`
+		OldStatus FileStatus `json:"old_status,omitempty"`
+		Actor     string     `json:"actor"`
+		Reason    string     `json:"reason,omitempty"`
+	} `json:"data"`
+}
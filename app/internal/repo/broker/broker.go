@@ -0,0 +1,60 @@
+package broker
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sync"
+)
+
+var (
+	driver = flag.String("brokerDriver", "", "message broker for outbox events: redis, nats or memory; empty disables publishing")
+	broker Broker
+	once   sync.Once
+)
+
+// Message is one event. ID is unique per event and lets consumers drop redeliveries; Key groups
+// related events, e.g. all events of one file.
+type Message struct {
+	ID      string
+	Topic   string
+	Key     string
+	Payload []byte
+	Headers map[string]string
+}
+
+// Broker publishes messages. Publish returns only after the broker acknowledged storing the message,
+// which is what gives the outbox relay its at-least-once guarantee.
+type Broker interface {
+	Publish(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+func Enabled() bool {
+	return *driver != ""
+}
+
+func initBroker() {
+	switch *driver {
+	case "redis":
+		broker = NewRedis()
+	case "nats":
+		b, err := NewNATS()
+		if err != nil {
+			log.Fatal(err)
+		}
+		broker = b
+	case "memory":
+		broker = NewMemory()
+	default:
+		log.Fatalf("unknown broker driver: %s", *driver)
+	}
+}
+
+func GetBroker() Broker {
+	once.Do(func() {
+		initBroker()
+	})
+
+	return broker
+}
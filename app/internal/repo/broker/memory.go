@@ -0,0 +1,64 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// memoryCapacity is how many of the latest messages Memory keeps.
+const memoryCapacity = 1000
+
+// Memory keeps the latest published messages in process, in a ring buffer of memoryCapacity.
+// It stands in for a real broker in local setups and tests.
+type Memory struct {
+	mu          sync.Mutex
+	messages    []*Message
+	next        int
+	subscribers []func(*Message)
+}
+
+func NewMemory() *Memory {
+	return &Memory{messages: make([]*Message, 0, memoryCapacity)}
+}
+
+func (m *Memory) Publish(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if len(m.messages) < cap(m.messages) {
+		m.messages = append(m.messages, msg)
+	} else {
+		m.messages[m.next] = msg
+	}
+	m.next = (m.next + 1) % cap(m.messages)
+	subscribers := append([]func(*Message){}, m.subscribers...)
+	m.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(msg)
+	}
+	return nil
+}
+
+// Subscribe registers fn for every message published from now on.
+func (m *Memory) Subscribe(fn func(*Message)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Messages returns the kept messages, oldest first.
+func (m *Memory) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) < cap(m.messages) {
+		return append([]*Message(nil), m.messages...)
+	}
+	return append(append([]*Message(nil), m.messages[m.next:]...), m.messages[:m.next]...)
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
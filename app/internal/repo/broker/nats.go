@@ -0,0 +1,262 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	natsURL   = flag.String("natsURL", "nats://127.0.0.1:4222", "NATS server used by the nats broker")
+	natsToken = flag.String("natsToken", "", "NATS auth token, user and password can be given in natsURL instead")
+)
+
+// NATS publishes to JetStream with HPUB over the plain NATS client protocol. Every publish carries a
+// reply subject under the connection's inbox and only succeeds once the stream answers with a PubAck,
+// so the message is known to be stored. The message ID is sent as Nats-Msg-Id, which JetStream uses
+// to drop duplicates on redelivery.
+type NATS struct {
+	addr     string
+	user     string
+	password string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	inbox  string
+	seq    uint64
+}
+
+// natsPubAck is JetStream's answer to a publish.
+type natsPubAck struct {
+	Stream    string `json:"stream"`
+	Seq       uint64 `json:"seq"`
+	Duplicate bool   `json:"duplicate"`
+	Error     *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+func NewNATS() (*NATS, error) {
+	u, err := url.Parse(*natsURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid natsURL: %q", *natsURL)
+	}
+	n := &NATS{addr: u.Host}
+	if u.User != nil {
+		n.user = u.User.Username()
+		n.password, _ = u.User.Password()
+	}
+	if u.Port() == "" {
+		n.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return n, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, msg *Message) error {
+	headers := map[string]string{"Nats-Msg-Id": msg.ID, "Nats-Msg-Key": msg.Key}
+	for name, value := range msg.Headers {
+		headers[name] = value
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var header strings.Builder
+	header.WriteString("NATS/1.0\r\n")
+	for _, name := range names {
+		header.WriteString(name + ": " + headers[name] + "\r\n")
+	}
+	header.WriteString("\r\n")
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	n.conn.SetDeadline(deadline)
+
+	n.seq++
+	reply := fmt.Sprintf("%s.%d", n.inbox, n.seq)
+	w := bufio.NewWriter(n.conn)
+	fmt.Fprintf(w, "HPUB %s %s %d %d\r\n%s", msg.Topic, reply, header.Len(), header.Len()+len(msg.Payload), header.String())
+	w.Write(msg.Payload)
+	w.WriteString("\r\n")
+	err := w.Flush()
+	if err == nil {
+		err = n.waitAck(reply)
+	}
+	if err != nil {
+		// A late ack must not be taken for the next publish's, so the connection starts over.
+		n.conn.Close()
+		n.conn = nil
+	}
+	return err
+}
+
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	return err
+}
+
+func (n *NATS) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	n.conn, n.reader = conn, bufio.NewReader(conn)
+
+	line, err := n.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		n.conn = nil
+		return errors.New("nats: server did not send INFO")
+	}
+
+	options, err := json.Marshal(map[string]any{
+		"verbose":       false,
+		"pedantic":      false,
+		"headers":       true,
+		"no_responders": true,
+		"protocol":      1,
+		"name":          "video-service",
+		"lang":          "go",
+		"user":          n.user,
+		"pass":          n.password,
+		"auth_token":    *natsToken,
+	})
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 12)
+	if _, err = rand.Read(id); err != nil {
+		return err
+	}
+	n.inbox, n.seq = "_INBOX."+hex.EncodeToString(id), 0
+	if _, err = fmt.Fprintf(conn, "CONNECT %s\r\nSUB %s.* 1\r\nPING\r\n", options, n.inbox); err == nil {
+		err = n.waitPong()
+	}
+	if err != nil {
+		conn.Close()
+		n.conn = nil
+		return err
+	}
+	return nil
+}
+
+// waitPong reads until the server's PONG, answering its keep-alive PINGs on the way.
+func (n *NATS) waitPong() error {
+	for {
+		line, err := n.readControl()
+		if err != nil {
+			return err
+		}
+		if line == "PONG" {
+			return nil
+		}
+	}
+}
+
+// waitAck reads until the message sent to reply and checks that it is a successful PubAck.
+func (n *NATS) waitAck(reply string) error {
+	for {
+		line, err := n.readControl()
+		if err != nil {
+			return err
+		}
+		// MSG <subject> <sid> [reply-to] <#bytes> and HMSG <subject> <sid> [reply-to] <#header bytes> <#total bytes>
+		fields := strings.Fields(line)
+		var headerLen, total int
+		switch {
+		case len(fields) >= 4 && fields[0] == "MSG":
+			total, err = strconv.Atoi(fields[len(fields)-1])
+		case len(fields) >= 5 && fields[0] == "HMSG":
+			if headerLen, err = strconv.Atoi(fields[len(fields)-2]); err == nil {
+				total, err = strconv.Atoi(fields[len(fields)-1])
+			}
+		default:
+			continue
+		}
+		if err != nil || headerLen > total {
+			return fmt.Errorf("nats: malformed message: %q", line)
+		}
+		data := make([]byte, total+2)
+		if _, err = io.ReadFull(n.reader, data); err != nil {
+			return err
+		}
+		if fields[1] != reply {
+			continue
+		}
+
+		// A status header without a body, e.g. "NATS/1.0 503" when no stream listens on the subject.
+		if status := strings.Fields(strings.SplitN(string(data[:headerLen]), "\r\n", 2)[0]); len(status) > 1 && headerLen == total {
+			if status[1] == "503" {
+				return errors.New("nats: no JetStream stream for the subject")
+			}
+			return fmt.Errorf("nats: publish answered with status %s", strings.Join(status[1:], " "))
+		}
+		var ack natsPubAck
+		if err = json.Unmarshal(data[headerLen:total], &ack); err != nil {
+			return fmt.Errorf("nats: invalid PubAck: %w", err)
+		}
+		if ack.Error != nil {
+			return fmt.Errorf("nats: publish rejected: %s (%d)", ack.Error.Description, ack.Error.Code)
+		}
+		if ack.Stream == "" {
+			return errors.New("nats: PubAck without a stream")
+		}
+		return nil
+	}
+}
+
+// readControl returns the next protocol line, answering keep-alive PINGs and failing on -ERR.
+func (n *NATS) readControl() (string, error) {
+	for {
+		line, err := n.reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			if _, err = n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return "", err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return "", fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		default:
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	redisAddr         = flag.String("redisAddr", "127.0.0.1:6379", "Redis address used by the redis broker")
+	redisPassword     = flag.String("redisPassword", "", "Redis password")
+	redisDB           = flag.Int("redisDB", 0, "Redis database")
+	redisStreamMaxLen = flag.Int("redisStreamMaxLen", 100000, "approximate length Redis streams are trimmed to, 0 disables trimming")
+)
+
+// Redis appends messages to a Redis Stream named after the topic with XADD over a single RESP connection.
+type Redis struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewRedis() *Redis {
+	return &Redis{}
+}
+
+func (r *Redis) Publish(ctx context.Context, msg *Message) error {
+	args := []string{"XADD", msg.Topic}
+	if *redisStreamMaxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.Itoa(*redisStreamMaxLen))
+	}
+	args = append(args, "*", "id", msg.ID, "key", msg.Key, "payload", string(msg.Payload))
+
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "h:"+name, msg.Headers[name])
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.command(ctx, args...)
+	return err
+}
+
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+// command sends one command and reads its reply, dropping the connection on any I/O error
+// so the next call reconnects.
+func (r *Redis) command(ctx context.Context, args ...string) (string, error) {
+	if r.conn == nil {
+		if err := r.connect(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	r.conn.SetDeadline(deadline)
+
+	reply, err := r.roundTrip(args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		r.conn.Close()
+		r.conn = nil
+	}
+	return reply, err
+}
+
+func (r *Redis) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", *redisAddr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	r.conn, r.reader = conn, bufio.NewReader(conn)
+
+	if *redisPassword != "" {
+		if _, err = r.roundTrip([]string{"AUTH", *redisPassword}); err != nil {
+			r.conn.Close()
+			r.conn = nil
+			return err
+		}
+	}
+	if *redisDB != 0 {
+		if _, err = r.roundTrip([]string{"SELECT", strconv.Itoa(*redisDB)}); err != nil {
+			r.conn.Close()
+			r.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (r *Redis) roundTrip(args []string) (string, error) {
+	w := bufio.NewWriter(r.conn)
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return r.readReply()
+}
+
+func (r *Redis) readReply() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 3 {
+		return "", errors.New("redis: malformed reply")
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", errors.New("redis: malformed bulk reply")
+		}
+		if size < 0 {
+			return "", nil
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r.reader, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:size]), nil
+	default:
+		return "", fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,106 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"strings"
+)
+
+// videoDetailsColumns selects the user-editable fields of files aliased as f, read with videoDetails.
+const videoDetailsColumns = `COALESCE(f.title, ''), COALESCE(f.description, ''), f.tags, COALESCE(f.language, ''),
+	COALESCE(f.custom_fields, ''), f.folder_id`
+
+type videoDetails struct {
+	tags         string
+	customFields string
+	folderID     sql.NullInt64
+}
+
+func (d *videoDetails) dest(resp *models.InfoVideosResp) []any {
+	return []any{&resp.Title, &resp.Description, &d.tags, &resp.Language, &d.customFields, &d.folderID}
+}
+
+func (d *videoDetails) apply(resp *models.InfoVideosResp) {
+	resp.Tags = splitTags(d.tags)
+	if d.customFields != "" {
+		resp.CustomFields = json.RawMessage(d.customFields)
+	}
+	if d.folderID.Valid {
+		id := int(d.folderID.Int64)
+		resp.FolderId = &id
+	}
+}
+
+func splitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+	return strings.Split(tags, ",")
+}
+
+// UpdateVideoDetails writes the fields present in req, which the caller has already validated, and records
+// the change in the outbox. Deleted files cannot be edited.
+func (s *Storage) UpdateVideoDetails(id, userID int, req *models.VideoDetailsReq) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status models.FileStatus
+	var isStream bool
+	query := `SELECT status, is_stream FROM files WHERE id = ? AND user_id = ? FOR UPDATE`
+	if err = tx.QueryRow(query, id, userID).Scan(&status, &isStream); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVideoNotFound
+		}
+		return err
+	}
+	if status == models.StatusDeleted {
+		return ErrVideoNotFound
+	}
+
+	var set []string
+	var args []any
+	if req.Title != nil {
+		set = append(set, "title = NULLIF(?, '')")
+		args = append(args, *req.Title)
+	}
+	if req.Description != nil {
+		set = append(set, "description = NULLIF(?, '')")
+		args = append(args, *req.Description)
+	}
+	if req.Tags != nil {
+		set = append(set, "tags = ?")
+		args = append(args, strings.Join(*req.Tags, ","))
+	}
+	if req.Language != nil {
+		set = append(set, "language = NULLIF(?, '')")
+		args = append(args, *req.Language)
+	}
+	if req.CustomFields != nil {
+		set = append(set, "custom_fields = NULLIF(?, 'null')")
+		args = append(args, string(req.CustomFields))
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	query = `UPDATE files SET ` + strings.Join(set, ", ") + ` WHERE id = ?`
+	if _, err = tx.Exec(query, append(args, id)...); err != nil {
+		return err
+	}
+	event := &models.FileEvent{
+		FileId:   id,
+		UserId:   userID,
+		Status:   status,
+		Actor:    models.UserActor(userID),
+		IsStream: isStream,
+	}
+	if err = writeFileEvent(tx, models.OutboxFileUpdated, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
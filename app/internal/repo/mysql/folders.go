@@ -0,0 +1,495 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/go-sql-driver/mysql"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrFolderNotFound = errors.New("folder not found")
+	ErrFolderExists   = errors.New("a folder with this name already exists here")
+	ErrFolderNotEmpty = errors.New("folder is not empty")
+	ErrFolderCycle    = errors.New("a folder cannot be moved into itself or its subfolders")
+	ErrFolderTooDeep  = errors.New("folder nesting too deep")
+)
+
+// DeletedVideo is a video removed together with a folder, whose stored files are still to be deleted.
+type DeletedVideo struct {
+	Id       int
+	FilePath string
+}
+
+func (s *Storage) CountFolders(userID int) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM folders WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+// CreateFolder inserts the folder below its parent, if any, keeping the tree within maxDepth levels.
+func (s *Storage) CreateFolder(folder *models.Folder, maxDepth int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFolderTree(tx, folder.UserId); err != nil {
+		return err
+	}
+	if folder.ParentId != nil {
+		ancestors, err := folderAncestors(tx, *folder.ParentId, folder.UserId)
+		if err != nil {
+			return err
+		}
+		if len(ancestors)+1 > maxDepth {
+			return fmt.Errorf("%w: at most %d levels", ErrFolderTooDeep, maxDepth)
+		}
+	}
+
+	query := `INSERT INTO folders (user_id, parent_id, name) VALUES (?, ?, ?)`
+	result, err := tx.Exec(query, folder.UserId, folder.ParentId, folder.Name)
+	if err != nil {
+		return folderWriteError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	folder.Id = int(id)
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	folder.CreatedAt = time.Now().UTC()
+	folder.UpdatedAt = folder.CreatedAt
+	return nil
+}
+
+// GetFolders returns all of the user's folders, parents before their children.
+func (s *Storage) GetFolders(userID int) ([]*models.Folder, error) {
+	query := `
+	WITH RECURSIVE tree (id, depth) AS (
+		SELECT id, 0 FROM folders WHERE user_id = ? AND parent_id IS NULL
+		UNION ALL
+		SELECT f.id, t.depth + 1 FROM folders f INNER JOIN tree t ON f.parent_id = t.id
+	)
+	SELECT d.id, d.parent_id, d.name,
+		(SELECT COUNT(*) FROM files WHERE folder_id = d.id AND status <> 'deleted'),
+		CAST(UNIX_TIMESTAMP(d.created_at) * 1000 AS SIGNED), CAST(UNIX_TIMESTAMP(d.updated_at) * 1000 AS SIGNED)
+	FROM tree t
+	INNER JOIN folders d ON d.id = t.id
+	ORDER BY t.depth, d.name, d.id
+`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []*models.Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folder.UserId = userID
+		folders = append(folders, folder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (s *Storage) GetFolder(id, userID int) (*models.Folder, error) {
+	query := `
+	SELECT d.id, d.parent_id, d.name,
+		(SELECT COUNT(*) FROM files WHERE folder_id = d.id AND status <> 'deleted'),
+		CAST(UNIX_TIMESTAMP(d.created_at) * 1000 AS SIGNED), CAST(UNIX_TIMESTAMP(d.updated_at) * 1000 AS SIGNED)
+	FROM folders d
+	WHERE d.id = ?
+	AND d.user_id = ?
+`
+	folder, err := scanFolder(s.db.QueryRow(query, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
+	folder.UserId = userID
+	return folder, nil
+}
+
+func scanFolder(row rowScanner) (*models.Folder, error) {
+	var folder models.Folder
+	var parentID sql.NullInt64
+	var createdMs, updatedMs int64
+	if err := row.Scan(&folder.Id, &parentID, &folder.Name, &folder.VideoCount, &createdMs, &updatedMs); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		folder.ParentId = &id
+	}
+	folder.CreatedAt = time.UnixMilli(createdMs).UTC()
+	folder.UpdatedAt = time.UnixMilli(updatedMs).UTC()
+	return &folder, nil
+}
+
+// UpdateFolder renames the folder and, when parent is set, moves it. A folder cannot move into its own
+// subtree and the moved subtree must still fit within maxDepth levels.
+func (s *Storage) UpdateFolder(id, userID int, name *string, parent models.OptionalID, maxDepth int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFolderTree(tx, userID); err != nil {
+		return err
+	}
+	if err = lockFolder(tx, id, userID); err != nil {
+		return err
+	}
+
+	var set []string
+	var args []any
+	if name != nil {
+		set = append(set, "name = ?")
+		args = append(args, *name)
+	}
+	if parent.Set {
+		depth := 0
+		if parent.Id != nil {
+			ancestors, err := folderAncestors(tx, *parent.Id, userID)
+			if err != nil {
+				return err
+			}
+			for _, ancestor := range ancestors {
+				if ancestor == id {
+					return ErrFolderCycle
+				}
+			}
+			depth = len(ancestors)
+		}
+		subtree, err := folderSubtree(tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if depth+subtreeHeight(subtree) > maxDepth {
+			return fmt.Errorf("%w: at most %d levels", ErrFolderTooDeep, maxDepth)
+		}
+		set = append(set, "parent_id = ?")
+		args = append(args, parent.Id)
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	query := `UPDATE folders SET ` + strings.Join(set, ", ") + ` WHERE id = ?`
+	if _, err = tx.Exec(query, append(args, id)...); err != nil {
+		return folderWriteError(err)
+	}
+	return tx.Commit()
+}
+
+// DeleteFolder removes the folder according to policy. With delete_contents the videos of the whole
+// subtree are deleted in the same transaction and returned so their stored files can be removed.
+func (s *Storage) DeleteFolder(id, userID int, policy string) (*models.FolderDeleteResp, []DeletedVideo, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	if err = lockFolderTree(tx, userID); err != nil {
+		return nil, nil, err
+	}
+	var parentID sql.NullInt64
+	query := `SELECT parent_id FROM folders WHERE id = ? AND user_id = ? FOR UPDATE`
+	if err = tx.QueryRow(query, id, userID).Scan(&parentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrFolderNotFound
+		}
+		return nil, nil, err
+	}
+	subtree, err := folderSubtree(tx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := &models.FolderDeleteResp{DeletedFolders: []int{}, DeletedVideos: []int{}}
+	var deleted []DeletedVideo
+
+	switch policy {
+	case models.FolderDeleteRestrict:
+		var videos int
+		query = `SELECT COUNT(*) FROM files WHERE folder_id = ? AND status <> 'deleted'`
+		if err = tx.QueryRow(query, id).Scan(&videos); err != nil {
+			return nil, nil, err
+		}
+		if len(subtree) > 1 || videos > 0 {
+			return nil, nil, fmt.Errorf("%w: %d subfolders, %d videos", ErrFolderNotEmpty, len(subtree)-1, videos)
+		}
+		resp.DeletedFolders = append(resp.DeletedFolders, id)
+	case models.FolderDeleteMoveToParent:
+		var parent any
+		if parentID.Valid {
+			parent = parentID.Int64
+		}
+		if _, err = tx.Exec(`UPDATE folders SET parent_id = ? WHERE parent_id = ?`, parent, id); err != nil {
+			return nil, nil, folderWriteError(err)
+		}
+		if _, err = tx.Exec(`UPDATE files SET folder_id = ? WHERE folder_id = ?`, parent, id); err != nil {
+			return nil, nil, err
+		}
+		resp.DeletedFolders = append(resp.DeletedFolders, id)
+	case models.FolderDeleteDeleteContents:
+		if deleted, err = deleteFolderVideos(tx, subtree, userID); err != nil {
+			return nil, nil, err
+		}
+		for _, video := range deleted {
+			resp.DeletedVideos = append(resp.DeletedVideos, video.Id)
+		}
+		// The parent foreign key restricts deletes, so the subtree goes deepest level first.
+		slices.SortStableFunc(subtree, func(a, b [2]int) int { return b[1] - a[1] })
+		for _, folder := range subtree {
+			resp.DeletedFolders = append(resp.DeletedFolders, folder[0])
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown folder delete policy %q", policy)
+	}
+
+	for _, folderID := range resp.DeletedFolders {
+		if _, err = tx.Exec(`DELETE FROM folders WHERE id = ?`, folderID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return resp, deleted, nil
+}
+
+func deleteFolderVideos(tx *sql.Tx, subtree [][2]int, userID int) ([]DeletedVideo, error) {
+	args := []any{userID}
+	for _, folder := range subtree {
+		args = append(args, folder[0])
+	}
+	query := `SELECT id, filepath FROM files WHERE user_id = ? AND status <> 'deleted' AND folder_id IN (?` +
+		strings.Repeat(", ?", len(subtree)-1) + `) ORDER BY id FOR UPDATE`
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var videos []DeletedVideo
+	for rows.Next() {
+		var video DeletedVideo
+		if err = rows.Scan(&video.Id, &video.FilePath); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	change := models.StatusChange{Actor: models.UserActor(userID), Reason: "folder deleted"}
+	for _, video := range videos {
+		if err = deleteVideo(tx, fmt.Sprintf("_%s_%d", "deleted", video.Id), video.Id, userID, change); err != nil {
+			return nil, err
+		}
+	}
+	return videos, nil
+}
+
+// SetVideosFolder puts the given videos into the folder. Every video must belong to the user and not be deleted.
+func (s *Storage) SetVideosFolder(userID, folderID int, videoIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFolder(tx, folderID, userID); err != nil {
+		return err
+	}
+
+	args := []any{userID}
+	for _, id := range videoIDs {
+		args = append(args, id)
+	}
+	in := `(?` + strings.Repeat(", ?", len(videoIDs)-1) + `)`
+	query := `SELECT id FROM files WHERE user_id = ? AND status <> 'deleted' AND id IN ` + in + ` FOR UPDATE`
+	found, err := queryIDs(tx, query, args...)
+	if err != nil {
+		return err
+	}
+	if len(found) != len(videoIDs) {
+		return ErrVideoNotFound
+	}
+
+	query = `UPDATE files SET folder_id = ? WHERE user_id = ? AND id IN ` + in
+	if _, err = tx.Exec(query, append([]any{folderID}, args...)...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveVideosFromFolder takes the videos out of the folder. Either all of them are in it or none is moved.
+func (s *Storage) RemoveVideosFromFolder(userID, folderID int, videoIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFolder(tx, folderID, userID); err != nil {
+		return err
+	}
+	args := []any{userID, folderID}
+	for _, id := range videoIDs {
+		args = append(args, id)
+	}
+	query := `UPDATE files SET folder_id = NULL WHERE user_id = ? AND folder_id = ? AND id IN (?` +
+		strings.Repeat(", ?", len(videoIDs)-1) + `)`
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected != int64(len(videoIDs)) {
+		return ErrVideoNotFound
+	}
+	return tx.Commit()
+}
+
+// GetFolderSubtree returns the ids of the folder and all its subfolders.
+func (s *Storage) GetFolderSubtree(id, userID int) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	subtree, err := folderSubtree(tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(subtree))
+	for i, folder := range subtree {
+		ids[i] = folder[0]
+	}
+	return ids, nil
+}
+
+// lockFolderTree locks all folders of the user. Changes to the shape of the tree take it first, so
+// the ancestor and subtree reads they check against cannot be invalidated by a concurrent move.
+func lockFolderTree(tx *sql.Tx, userID int) error {
+	_, err := queryIDs(tx, `SELECT id FROM folders WHERE user_id = ? FOR UPDATE`, userID)
+	return err
+}
+
+func lockFolder(tx *sql.Tx, id, userID int) error {
+	var locked int
+	err := tx.QueryRow(`SELECT id FROM folders WHERE id = ? AND user_id = ? FOR UPDATE`, id, userID).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrFolderNotFound
+	}
+	return err
+}
+
+// folderAncestors returns the folder followed by its ancestors up to the root.
+func folderAncestors(tx *sql.Tx, id, userID int) ([]int, error) {
+	query := `
+	WITH RECURSIVE ancestors (id, parent_id) AS (
+		SELECT id, parent_id FROM folders WHERE id = ? AND user_id = ?
+		UNION ALL
+		SELECT f.id, f.parent_id FROM folders f INNER JOIN ancestors a ON f.id = a.parent_id
+	)
+	SELECT id FROM ancestors
+`
+	ids, err := queryIDs(tx, query, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrFolderNotFound
+	}
+	return ids, nil
+}
+
+// folderSubtree returns the folder and its descendants as (id, level) pairs, the folder itself at level 1.
+func folderSubtree(tx *sql.Tx, id, userID int) ([][2]int, error) {
+	query := `
+	WITH RECURSIVE subtree (id, level) AS (
+		SELECT id, 1 FROM folders WHERE id = ? AND user_id = ?
+		UNION ALL
+		SELECT f.id, s.level + 1 FROM folders f INNER JOIN subtree s ON f.parent_id = s.id
+	)
+	SELECT id, level FROM subtree
+`
+	rows, err := tx.Query(query, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subtree [][2]int
+	for rows.Next() {
+		var folder [2]int
+		if err = rows.Scan(&folder[0], &folder[1]); err != nil {
+			return nil, err
+		}
+		subtree = append(subtree, folder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(subtree) == 0 {
+		return nil, ErrFolderNotFound
+	}
+	return subtree, nil
+}
+
+func subtreeHeight(subtree [][2]int) int {
+	height := 0
+	for _, folder := range subtree {
+		height = max(height, folder[1])
+	}
+	return height
+}
+
+func queryIDs(tx *sql.Tx, query string, args ...any) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func folderWriteError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrFolderExists
+	}
+	return err
+}
@@ -0,0 +1,426 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"time"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrLeaseLost   = errors.New("job lease lost")
+	ErrRetryLimit  = errors.New("retry limit reached")
+)
+
+// EnqueueConversionJob (re)queues a conversion job for the file and moves the file to conv.
+func (s *Storage) EnqueueConversionJob(fileID, maxAttempts int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	change := models.StatusChange{Actor: models.ActorSystem, Reason: "queued for conversion"}
+	if err = transitionStatus(tx, fileID, 0, models.StatusConv, change, ""); err != nil {
+		return err
+	}
+	if err = enqueueJob(tx, fileID, maxAttempts, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RetryFile queues an errored file again. The job becomes available once retryDelay(retries) seconds have
+// passed since the last failure, and files already retried maxRetries times are refused with ErrRetryLimit.
+func (s *Storage) RetryFile(fileID, userID, maxRetries, maxAttempts int, retryDelay func(retries int) int) (*models.RetryResp, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resp, err := retryFile(tx, fileID, userID, maxRetries, maxAttempts, retryDelay)
+	if err != nil {
+		return nil, err
+	}
+	return resp, tx.Commit()
+}
+
+// RequeueFailedStreams retries every errored stream file of the user that is still within the retry limit.
+func (s *Storage) RequeueFailedStreams(userID, maxRetries, maxAttempts int, retryDelay func(retries int) int) ([]*models.RetryResp, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT id FROM files WHERE status = 'error' AND is_stream = 1 AND user_id = ? AND retry_count < ?`
+	rows, err := tx.Query(query, userID, maxRetries)
+	if err != nil {
+		return nil, err
+	}
+	var fileIDs []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		fileIDs = append(fileIDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := []*models.RetryResp{}
+	for _, fileID := range fileIDs {
+		resp, err := retryFile(tx, fileID, userID, maxRetries, maxAttempts, retryDelay)
+		if errors.Is(err, ErrRetryLimit) || errors.Is(err, ErrIllegalTransition) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, resp)
+	}
+	return results, tx.Commit()
+}
+
+func retryFile(tx *sql.Tx, fileID, userID, maxRetries, maxAttempts int, retryDelay func(retries int) int) (*models.RetryResp, error) {
+	query := `
+	SELECT status, retry_count, COALESCE(TIMESTAMPDIFF(SECOND, last_failed_at, CURRENT_TIMESTAMP(3)), 0)
+	FROM files
+	WHERE id = ? AND user_id = ?
+	FOR UPDATE
+`
+	var status models.FileStatus
+	var retries, sinceFailure int
+	if err := tx.QueryRow(query, fileID, userID).Scan(&status, &retries, &sinceFailure); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVideoNotFound
+		}
+		return nil, err
+	}
+	// Only failed conversions are retried; other files reach conv through the upload flow.
+	if status != models.StatusError {
+		return nil, &TransitionError{Current: status, Next: models.StatusConv}
+	}
+	if retries >= maxRetries {
+		return nil, fmt.Errorf("%w: %d of %d retries used", ErrRetryLimit, retries, maxRetries)
+	}
+
+	change := models.StatusChange{Actor: models.UserActor(userID), Reason: "retry requested"}
+	if err := transitionStatus(tx, fileID, userID, models.StatusConv, change, ", retry_count = retry_count + 1"); err != nil {
+		return nil, err
+	}
+	delay := max(retryDelay(retries+1)-sinceFailure, 0)
+	if err := enqueueJob(tx, fileID, maxAttempts, delay); err != nil {
+		return nil, err
+	}
+
+	var availableMs int64
+	query = `SELECT CAST(UNIX_TIMESTAMP(available_at) * 1000 AS SIGNED) FROM conversion_jobs WHERE file_id = ?`
+	if err := tx.QueryRow(query, fileID).Scan(&availableMs); err != nil {
+		return nil, err
+	}
+	return &models.RetryResp{FileId: fileID, RetryCount: retries + 1, AvailableAt: time.UnixMilli(availableMs).UTC()}, nil
+}
+
+func enqueueJob(tx *sql.Tx, fileID, maxAttempts, delaySeconds int) error {
+	query := `
+		INSERT INTO conversion_jobs (file_id, max_attempts, available_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND)
+		ON DUPLICATE KEY UPDATE
+			status = 'queued', attempts = 0, max_attempts = VALUES(max_attempts), available_at = VALUES(available_at),
+			worker_id = NULL, lease_token = NULL, lease_expires_at = NULL, last_error = NULL
+	`
+	if _, err := tx.Exec(query, fileID, maxAttempts, delaySeconds); err != nil {
+		return fmt.Errorf("failed to enqueue conversion job: %w", err)
+	}
+	return nil
+}
+
+// ClaimConversionJob leases the oldest available job to a worker. Rows locked by concurrent
+// claims are skipped, so two workers never receive the same job. It returns nil when the queue is empty.
+func (s *Storage) ClaimConversionJob(workerID, leaseToken string, leaseSeconds int) (*models.ConversionJob, error) {
+	for {
+		job, err := s.claimConversionJob(workerID, leaseToken, leaseSeconds)
+		if !errors.Is(err, errStaleJob) {
+			return job, err
+		}
+	}
+}
+
+// errStaleJob means the claimed job's file can no longer be converted; the job was failed and the claim is retried.
+var errStaleJob = errors.New("stale conversion job")
+
+func (s *Storage) claimConversionJob(workerID, leaseToken string, leaseSeconds int) (*models.ConversionJob, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT j.id, j.file_id, f.filepath, f.is_stream, j.attempts, j.max_attempts
+	FROM conversion_jobs j
+	INNER JOIN files f ON f.id = j.file_id
+	WHERE j.status = 'queued'
+	AND j.available_at <= CURRENT_TIMESTAMP(3)
+	ORDER BY j.available_at, j.id
+	LIMIT 1
+	FOR UPDATE OF j SKIP LOCKED
+`
+	var job models.ConversionJob
+	err = tx.QueryRow(query).Scan(&job.Id, &job.FileId, &job.FilePath, &job.IsStream, &job.Attempts, &job.MaxAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE conversion_jobs
+		SET status = 'leased', attempts = attempts + 1, worker_id = ?, lease_token = ?,
+			lease_expires_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND
+		WHERE id = ?
+	`
+	if _, err = tx.Exec(query, workerID, leaseToken, leaseSeconds, job.Id); err != nil {
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+	change := models.StatusChange{Actor: models.WorkerActor(workerID), Reason: "claimed for conversion"}
+	if err = transitionStatus(tx, job.FileId, 0, models.StatusProcess, change, ", conversion_attempts = conversion_attempts + 1"); err != nil {
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) {
+			return nil, err
+		}
+		query = `UPDATE conversion_jobs SET status = 'failed', worker_id = NULL, lease_token = NULL, lease_expires_at = NULL, last_error = ? WHERE id = ?`
+		if _, err = tx.Exec(query, "file is "+string(transitionErr.Current), job.Id); err != nil {
+			return nil, fmt.Errorf("failed to fail stale job: %w", err)
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, errStaleJob
+	}
+	var expiresMs int64
+	query = `SELECT CAST(UNIX_TIMESTAMP(lease_expires_at) * 1000 AS SIGNED) FROM conversion_jobs WHERE id = ?`
+	if err = tx.QueryRow(query, job.Id).Scan(&expiresMs); err != nil {
+		return nil, err
+	}
+	job.LeaseExpiresAt = time.UnixMilli(expiresMs)
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	job.Attempts++
+	job.WorkerId = workerID
+	job.LeaseToken = leaseToken
+	return &job, nil
+}
+
+func (s *Storage) ExtendJobLease(jobID int64, leaseToken string, leaseSeconds int) (*models.ConversionJob, error) {
+	query := `
+		UPDATE conversion_jobs
+		SET lease_expires_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND
+		WHERE id = ? AND status = 'leased' AND lease_token = ? AND lease_expires_at >= CURRENT_TIMESTAMP(3)
+	`
+	result, err := s.db.Exec(query, leaseSeconds, jobID, leaseToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if err = s.checkLeaseUpdated(result, jobID); err != nil {
+		return nil, err
+	}
+	return s.getConversionJob(jobID)
+}
+
+// CompleteConversionJob closes the job and marks the file done unless renditions registration already did.
+func (s *Storage) CompleteConversionJob(jobID int64, leaseToken string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	job, err := lockLeasedJob(tx, jobID, leaseToken)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE conversion_jobs
+		SET status = 'done', lease_token = NULL, lease_expires_at = NULL, last_error = NULL
+		WHERE id = ?
+	`
+	if _, err = tx.Exec(query, jobID); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	// Registering renditions already moves the file to done, and a deleted file stays deleted.
+	change := models.StatusChange{Actor: models.WorkerActor(job.WorkerId), Reason: "conversion completed"}
+	if err = transitionStatus(tx, job.FileId, 0, models.StatusDone, change, ", last_error = NULL"); err != nil && !errors.Is(err, ErrIllegalTransition) {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FailConversionJob puts a retryable job back in the queue after retryDelay(attempts) seconds. Jobs that
+// are not retryable or ran out of attempts are failed for good together with their file.
+func (s *Storage) FailConversionJob(jobID int64, leaseToken, lastError string, retryable bool, retryDelay func(attempts int) int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	job, err := lockLeasedJob(tx, jobID, leaseToken)
+	if err != nil {
+		return err
+	}
+
+	jobStatus, fileStatus, delay := models.JobFailed, models.StatusError, 0
+	if retryable && job.Attempts < job.MaxAttempts {
+		jobStatus, fileStatus, delay = models.JobQueued, models.StatusConv, retryDelay(job.Attempts)
+	}
+	query := `
+		UPDATE conversion_jobs
+		SET status = ?, available_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND,
+			worker_id = NULL, lease_token = NULL, lease_expires_at = NULL, last_error = ?
+		WHERE id = ?
+	`
+	if _, err = tx.Exec(query, jobStatus, delay, lastError, jobID); err != nil {
+		return fmt.Errorf("failed to fail job: %w", err)
+	}
+	change := models.StatusChange{Actor: models.WorkerActor(job.WorkerId), Reason: lastError}
+	if err = transitionStatus(tx, job.FileId, 0, fileStatus, change, failureSet(fileStatus), lastError); err != nil && !errors.Is(err, ErrIllegalTransition) {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RequeueExpiredJobs returns jobs whose worker stopped heartbeating to the queue. Jobs that already
+// used all their attempts are failed together with their file instead.
+func (s *Storage) RequeueExpiredJobs() (int64, error) {
+	var requeued int64
+	for {
+		found, err := s.requeueExpiredJob()
+		if err != nil || !found {
+			return requeued, err
+		}
+		requeued++
+	}
+}
+
+func (s *Storage) requeueExpiredJob() (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT id, file_id, attempts, max_attempts, COALESCE(worker_id, '')
+	FROM conversion_jobs
+	WHERE status = 'leased' AND lease_expires_at < CURRENT_TIMESTAMP(3)
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+`
+	var job models.ConversionJob
+	err = tx.QueryRow(query).Scan(&job.Id, &job.FileId, &job.Attempts, &job.MaxAttempts, &job.WorkerId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	jobStatus, fileStatus := models.JobQueued, models.StatusConv
+	if job.Attempts >= job.MaxAttempts {
+		jobStatus, fileStatus = models.JobFailed, models.StatusError
+	}
+	query = `
+		UPDATE conversion_jobs
+		SET status = ?, available_at = CURRENT_TIMESTAMP(3), worker_id = NULL, lease_token = NULL,
+			lease_expires_at = NULL, last_error = 'lease expired'
+		WHERE id = ?
+	`
+	if _, err = tx.Exec(query, jobStatus, job.Id); err != nil {
+		return false, fmt.Errorf("failed to requeue expired job: %w", err)
+	}
+	change := models.StatusChange{Actor: models.ActorSystem, Reason: "lease of " + models.WorkerActor(job.WorkerId) + " expired"}
+	if err = transitionStatus(tx, job.FileId, 0, fileStatus, change, failureSet(fileStatus), "lease expired"); err != nil && !errors.Is(err, ErrIllegalTransition) {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// failureSet stores the error on the file and, once the file gives up, when it failed so retries can back off.
+func failureSet(fileStatus models.FileStatus) string {
+	if fileStatus == models.StatusError {
+		return ", last_error = ?, last_failed_at = CURRENT_TIMESTAMP(3)"
+	}
+	return ", last_error = ?"
+}
+
+func (s *Storage) getConversionJob(jobID int64) (*models.ConversionJob, error) {
+	query := `
+	SELECT j.id, j.file_id, f.filepath, f.is_stream, COALESCE(j.worker_id, ''), j.attempts, j.max_attempts,
+		COALESCE(j.lease_token, ''), COALESCE(CAST(UNIX_TIMESTAMP(j.lease_expires_at) * 1000 AS SIGNED), 0)
+	FROM conversion_jobs j
+	INNER JOIN files f ON f.id = j.file_id
+	WHERE j.id = ?
+`
+	var job models.ConversionJob
+	var expiresMs int64
+	err := s.db.QueryRow(query, jobID).Scan(&job.Id, &job.FileId, &job.FilePath, &job.IsStream, &job.WorkerId,
+		&job.Attempts, &job.MaxAttempts, &job.LeaseToken, &expiresMs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expiresMs > 0 {
+		job.LeaseExpiresAt = time.UnixMilli(expiresMs)
+	}
+	return &job, nil
+}
+
+func (s *Storage) checkLeaseUpdated(result sql.Result, jobID int64) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	if _, err = s.getConversionJob(jobID); err != nil {
+		return err
+	}
+	return ErrLeaseLost
+}
+
+func lockLeasedJob(tx *sql.Tx, jobID int64, leaseToken string) (*models.ConversionJob, error) {
+	query := `
+	SELECT file_id, attempts, max_attempts, COALESCE(worker_id, ''),
+		status = 'leased' AND lease_token = ? AND lease_expires_at >= CURRENT_TIMESTAMP(3)
+	FROM conversion_jobs
+	WHERE id = ?
+	FOR UPDATE
+`
+	job := &models.ConversionJob{Id: jobID}
+	var leased bool
+	if err := tx.QueryRow(query, leaseToken, jobID).Scan(&job.FileId, &job.Attempts, &job.MaxAttempts, &job.WorkerId, &leased); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	if !leased {
+		return nil, ErrLeaseLost
+	}
+	return job, nil
+}
@@ -0,0 +1,139 @@
+package mysql
+
+import (
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"strings"
+	"time"
+)
+
+// videoSortColumns maps a sort to the expression it orders by and the placeholder a cursor value is
+// compared with; time cursors hold milliseconds.
+var videoSortColumns = map[string][2]string{
+	models.SortCreated:  {"f.created_at", "FROM_UNIXTIME(? / 1000)"},
+	models.SortUpdated:  {"f.updated_at", "FROM_UNIXTIME(? / 1000)"},
+	models.SortName:     {"f.filename", "?"},
+	models.SortSize:     {"COALESCE(f.size, 0)", "?"},
+	models.SortDuration: {"COALESCE(vm.duration_ms, 0)", "?"},
+}
+
+// ListVideos returns up to limit of the user's files in the requested order, starting after the cursor
+// when one is given. Ties on the sort value are broken by id so every page boundary is exact.
+func (s *Storage) ListVideos(userID int, req *models.VideoListReq, after *models.VideoCursor, limit int) ([]*models.InfoVideosResp, error) {
+	sortColumn, ok := videoSortColumns[req.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", req.Sort)
+	}
+	where, args := videoFilterClause(userID, &req.VideoFilter)
+
+	cmp, direction := ">", "ASC"
+	if req.Desc {
+		cmp, direction = "<", "DESC"
+	}
+	if after != nil {
+		where += fmt.Sprintf(" AND (%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND f.id %[2]s ?))", sortColumn[0], cmp, sortColumn[1])
+		args = append(args, after.Value, after.Value, after.Id)
+	}
+
+	query := `
+	SELECT f.id, f.filename, f.status, f.is_stream, f.filepath, f.status_ai, COALESCE(f.container, ''),
+		COALESCE(f.last_error, ''), f.conversion_attempts, f.retry_count, COALESCE(f.size, 0),
+		CAST(UNIX_TIMESTAMP(f.created_at) * 1000 AS SIGNED), CAST(UNIX_TIMESTAMP(f.updated_at) * 1000 AS SIGNED),
+		vm.file_id IS NOT NULL, COALESCE(vm.duration_ms, 0), COALESCE(vm.width, 0), COALESCE(vm.height, 0),
+		COALESCE(vm.frame_rate, 0), COALESCE(vm.video_codec, ''), COALESCE(vm.audio_codec, ''),
+		COALESCE(vm.bitrate, 0), COALESCE(vm.rotation, 0), ` + videoDetailsColumns + `
+	FROM files f
+	LEFT JOIN video_metadata vm ON vm.file_id = f.id
+	WHERE ` + where + `
+	ORDER BY ` + sortColumn[0] + ` ` + direction + `, f.id ` + direction + `
+	LIMIT ?
+`
+	rows, err := s.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.InfoVideosResp{}
+	for rows.Next() {
+		var resp models.InfoVideosResp
+		var filepathLocal string
+		var createdMs, updatedMs int64
+		var hasMetadata bool
+		var metadata models.VideoMetadata
+		var details videoDetails
+		dest := []any{&resp.Id, &resp.FileName, &resp.Status, &resp.IsStream, &filepathLocal, &resp.StatusAI, &resp.Container,
+			&resp.LastError, &resp.ConversionAttempts, &resp.RetryCount, &resp.Size, &createdMs, &updatedMs,
+			&hasMetadata, &metadata.DurationMs, &metadata.Width, &metadata.Height, &metadata.FrameRate,
+			&metadata.VideoCodec, &metadata.AudioCodec, &metadata.Bitrate, &metadata.Rotation}
+		if err = rows.Scan(append(dest, details.dest(&resp)...)...); err != nil {
+			return nil, err
+		}
+		details.apply(&resp)
+		if resp.Status != "deleted" {
+			resp.FilePath = filepathLocal
+		}
+		if hasMetadata {
+			resp.Metadata = &metadata
+		}
+		resp.CreatedAt = time.UnixMilli(createdMs).UTC()
+		resp.UpdatedAt = time.UnixMilli(updatedMs).UTC()
+		results = append(results, &resp)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CountVideos counts the user's files matching the filter, regardless of pagination.
+func (s *Storage) CountVideos(userID int, filter *models.VideoFilter) (int, error) {
+	where, args := videoFilterClause(userID, filter)
+	var total int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM files f WHERE `+where, args...).Scan(&total)
+	return total, err
+}
+
+func videoFilterClause(userID int, filter *models.VideoFilter) (string, []any) {
+	where := "f.user_id = ?"
+	args := []any{userID}
+	if filter.VideoId != 0 {
+		where += " AND f.id = ?"
+		args = append(args, filter.VideoId)
+	}
+	if len(filter.Statuses) > 0 {
+		where += " AND f.status IN (?" + strings.Repeat(", ?", len(filter.Statuses)-1) + ")"
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.IsStream != nil {
+		where += " AND f.is_stream = ?"
+		args = append(args, *filter.IsStream)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		where += " AND f.created_at >= FROM_UNIXTIME(? / 1000)"
+		args = append(args, filter.CreatedFrom.UnixMilli())
+	}
+	if !filter.CreatedTo.IsZero() {
+		where += " AND f.created_at < FROM_UNIXTIME(? / 1000)"
+		args = append(args, filter.CreatedTo.UnixMilli())
+	}
+	if filter.Unfiled {
+		where += " AND f.folder_id IS NULL"
+	} else if len(filter.FolderIds) > 0 {
+		where += " AND f.folder_id IN (?" + strings.Repeat(", ?", len(filter.FolderIds)-1) + ")"
+		for _, id := range filter.FolderIds {
+			args = append(args, id)
+		}
+	}
+	if filter.NamePrefix != "" {
+		where += " AND f.filename LIKE ?"
+		args = append(args, escapeLike(filter.NamePrefix)+"%")
+	}
+	return where, args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
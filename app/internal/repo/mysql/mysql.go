@@ -3,14 +3,15 @@ package mysql
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/Dimoonevs/video-service/app/internal/models"
-	"github.com/Dimoonevs/video-service/app/pkg/lib"
 	"github.com/go-sql-driver/mysql"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
 	"log"
+	"strings"
 	"sync"
 )
 
@@ -18,6 +19,11 @@ type Storage struct {
 	db *sql.DB
 }
 
+var (
+	ErrDuplicateFile = errors.New("duplicate file")
+	ErrVideoNotFound = errors.New("video not found")
+)
+
 var (
 	mysqlConnectionString = flag.String("SQLConnPassword", "user:pass@tcp(127.0.0.1:3306)/dbname?charset=utf8mb4,utf8", "DB connection")
 	storage               *Storage
@@ -45,15 +51,33 @@ func GetConnection() *Storage {
 }
 
 func (s *Storage) SetFilesData(filename, path string, isStream bool, userId int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertFile(tx, filename, path, isStream, userId)
+	if err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// insertFile registers a file in loading status together with its first history entry and event.
+func insertFile(tx *sql.Tx, filename, path string, isStream bool, userId int) (int, error) {
 	query := `
 		INSERT INTO files (filename, filepath, is_stream, status, user_id)
 		VALUES (?, ?, ?, 'loading', ?)
 	`
-	result, err := s.db.Exec(query, filename, path, isStream, userId)
+	result, err := tx.Exec(query, filename, path, isStream, userId)
 	if err != nil {
 		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
 			logrus.Errorf("duplicate entry error: %v", err)
-			return 0, err
+			return 0, fmt.Errorf("%w: %v", ErrDuplicateFile, err)
 		} else {
 			logrus.Errorf("failed to insert file data: %v", err)
 			return 0, err
@@ -65,106 +89,111 @@ func (s *Storage) SetFilesData(filename, path string, isStream bool, userId int)
 		logrus.Errorf("failed to get last insert ID: %v", err)
 		return 0, err
 	}
-	return int(id), nil
-}
-
-func (s *Storage) SetStatusByFilesID(filesID int, status models.FileStatus) {
-	query := `
-		UPDATE files 
-		SET status = ? 
-		WHERE id = ?
-	`
-
-	_, err := s.db.Exec(query, status, filesID)
-	if err != nil {
-		logrus.Errorf("failed to update status: %s", err)
+	change := models.StatusChange{Actor: models.UserActor(userId), Reason: "upload started"}
+	if err = recordStatusChange(tx, int(id), "", models.StatusLoading, change); err != nil {
+		return 0, err
 	}
-}
-
-func (s *Storage) SetStatusIntoConv(id int) error {
-	query := `
-		UPDATE files 
-		SET status = 'conv' 
-		WHERE status = 'error' AND is_stream = 1 and user_id = ?;
-	`
-
-	_, err := s.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to update status: %w", err)
+	event := &models.FileEvent{
+		FileId:   int(id),
+		UserId:   userId,
+		Status:   models.StatusLoading,
+		Actor:    change.Actor,
+		Reason:   change.Reason,
+		FileName: filename,
+		FilePath: path,
+		IsStream: isStream,
 	}
-
-	return nil
+	if err = writeFileEvent(tx, models.OutboxFileCreated, event); err != nil {
+		return 0, err
+	}
+	return int(id), nil
 }
 
-func (s *Storage) GetInfoVideos(status string, userID, videoID int) ([]*models.InfoVideosResp, error) {
-	query := fmt.Sprintf(`
-	SELECT id, filename, status, is_stream, filepath, status_ai
-	FROM files
-	WHERE user_id = %d
-`, userID)
-	var args []interface{}
-	if status != "" {
-		query += "AND status = ?"
-		args = append(args, status)
-	}
-	if videoID != 0 {
-		query += "AND id = ?"
-		args = append(args, videoID)
-	}
-	rows, err := s.db.Query(query, args...)
+func (s *Storage) SetStatusByFilesID(filesID int, status models.FileStatus, change models.StatusChange) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var results []*models.InfoVideosResp
-	for rows.Next() {
-		var resp models.InfoVideosResp
-		var filepathLocal string
-		if err = rows.Scan(&resp.Id, &resp.FileName, &resp.Status, &resp.IsStream, &filepathLocal, &resp.StatusAI); err != nil {
-			return nil, err
-		}
-		if resp.Status != "deleted" {
-			resp.FilePath = lib.GetVideoPublicLink(filepathLocal)
-		}
-		results = append(results, &resp)
+		return err
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+	defer tx.Rollback()
+
+	if err = transitionStatus(tx, filesID, 0, status, change, ""); err != nil {
+		return err
 	}
-	return results, nil
+	return tx.Commit()
 }
 
 func (s *Storage) GetInfoVideoById(id int, userID int) (*models.InfoVideosResp, error) {
 	query := `
-	SELECT id, filename, status, is_stream, filepath, status_ai
-	FROM files
-	WHERE id = ?
-	AND user_id = ?
+	SELECT f.id, f.filename, f.status, f.is_stream, f.filepath, f.status_ai, COALESCE(f.container, ''),
+		COALESCE(f.last_error, ''), f.conversion_attempts, f.retry_count, ` + videoDetailsColumns + `
+	FROM files f
+	WHERE f.id = ?
+	AND f.user_id = ?
 `
 	row := s.db.QueryRow(query, id, userID)
 
 	var videoInfo models.InfoVideosResp
-	if err := row.Scan(&videoInfo.Id, &videoInfo.FileName, &videoInfo.Status, &videoInfo.IsStream, &videoInfo.FilePath, &videoInfo.StatusAI); err != nil {
+	var details videoDetails
+	dest := []any{&videoInfo.Id, &videoInfo.FileName, &videoInfo.Status, &videoInfo.IsStream, &videoInfo.FilePath, &videoInfo.StatusAI, &videoInfo.Container,
+		&videoInfo.LastError, &videoInfo.ConversionAttempts, &videoInfo.RetryCount}
+	if err := row.Scan(append(dest, details.dest(&videoInfo)...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVideoNotFound
+		}
 		return nil, err
 	}
+	details.apply(&videoInfo)
 	return &videoInfo, nil
 }
 
+// DeleteVideo marks the file deleted and cancels its pending conversion job.
 func (s *Storage) DeleteVideo(newFilename string, id, userID int) error {
-	query := `
-	UPDATE files
-	SET status = 'deleted', filename = CONCAT(filename, ?)
-	WHERE id = ?
-	AND user_id = ?
-`
-	_, err := s.db.Exec(query, newFilename, id, userID)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	change := models.StatusChange{Actor: models.UserActor(userID), Reason: "deleted by owner"}
+	if err = deleteVideo(tx, newFilename, id, userID, change); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteVideo marks the file deleted, appending newFilename to its name, and cancels its pending conversion.
+func deleteVideo(tx *sql.Tx, newFilename string, id, userID int, change models.StatusChange) error {
+	if err := transitionStatus(tx, id, userID, models.StatusDeleted, change, ", filename = CONCAT(filename, ?)", newFilename); err != nil {
+		return err
+	}
+	query := `
+		UPDATE conversion_jobs
+		SET status = 'failed', worker_id = NULL, lease_token = NULL, lease_expires_at = NULL, last_error = 'file deleted'
+		WHERE file_id = ? AND status IN ('queued', 'leased')
+	`
+	if _, err := tx.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to cancel conversion job: %w", err)
+	}
 	return nil
 }
 
 func (s *Storage) GetVideoLinks(id int) ([]*models.VideoFormatLinksResp, error) {
+	return s.getVideoLinks(`f.user_id = ?`, id)
+}
+
+// GetVideoLinksByFileIDs returns the formats of those of the given files that are done.
+func (s *Storage) GetVideoLinksByFileIDs(ids []int) ([]*models.VideoFormatLinksResp, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return s.getVideoLinks(`f.id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`, args...)
+}
+
+func (s *Storage) getVideoLinks(where string, args ...any) ([]*models.VideoFormatLinksResp, error) {
 	query := `
 	SELECT 
 		f.id AS file_id, 
@@ -179,10 +208,9 @@ func (s *Storage) GetVideoLinks(id int) ([]*models.VideoFormatLinksResp, error)
 		video_formats vf ON fjvf.video_format_id = vf.id
 	WHERE 
 		f.status = 'done'
-	AND f.user_id = ?
-`
+	AND ` + where
 
-	rows, err := s.db.Query(query, id)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -212,3 +240,261 @@ func (s *Storage) GetVideoLinks(id int) ([]*models.VideoFormatLinksResp, error)
 
 	return results, nil
 }
+
+func (s *Storage) SetFileChecksum(filesID int, size int64, checksum string) error {
+	query := `
+		UPDATE files
+		SET size = ?, checksum = ?
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query, size, checksum, filesID)
+	if err != nil {
+		return fmt.Errorf("failed to update checksum: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetFileContainer(filesID int, container, brand string) error {
+	query := `
+		UPDATE files
+		SET container = ?, brand = ?
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query, container, brand, filesID)
+	if err != nil {
+		return fmt.Errorf("failed to update container: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetVideoMetadata(filesID int, metadata *models.VideoMetadata) error {
+	query := `
+		INSERT INTO video_metadata (file_id, duration_ms, width, height, frame_rate, video_codec, audio_codec, bitrate, rotation)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			duration_ms = VALUES(duration_ms), width = VALUES(width), height = VALUES(height),
+			frame_rate = VALUES(frame_rate), video_codec = VALUES(video_codec), audio_codec = VALUES(audio_codec),
+			bitrate = VALUES(bitrate), rotation = VALUES(rotation)
+	`
+	_, err := s.db.Exec(query, filesID, metadata.DurationMs, metadata.Width, metadata.Height, metadata.FrameRate,
+		metadata.VideoCodec, metadata.AudioCodec, metadata.Bitrate, metadata.Rotation)
+	if err != nil {
+		return fmt.Errorf("failed to insert video metadata: %w", err)
+	}
+	return nil
+}
+
+// GetVideoMetadata returns nil without an error when the file was never probed.
+func (s *Storage) GetVideoMetadata(filesID int) (*models.VideoMetadata, error) {
+	query := `
+	SELECT duration_ms, width, height, frame_rate, video_codec, audio_codec, bitrate, rotation
+	FROM video_metadata
+	WHERE file_id = ?
+`
+	var metadata models.VideoMetadata
+	err := s.db.QueryRow(query, filesID).Scan(&metadata.DurationMs, &metadata.Width, &metadata.Height, &metadata.FrameRate,
+		&metadata.VideoCodec, &metadata.AudioCodec, &metadata.Bitrate, &metadata.Rotation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+func (s *Storage) GetStoredFiles() ([]*models.StoredFile, error) {
+	query := `
+	SELECT id, user_id, filepath
+	FROM files
+	WHERE status != 'deleted'
+`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.StoredFile
+	for rows.Next() {
+		var file models.StoredFile
+		if err = rows.Scan(&file.Id, &file.UserId, &file.FilePath); err != nil {
+			return nil, err
+		}
+		results = append(results, &file)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *Storage) RelocateFile(filesID int, newPath, oldLinkPrefix, newLinkPrefix string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`UPDATE files SET filepath = ? WHERE id = ?`, newPath, filesID); err != nil {
+		return fmt.Errorf("failed to update filepath: %w", err)
+	}
+
+	query := `
+		UPDATE video_formats vf
+		INNER JOIN files_j_video_formats fjvf ON fjvf.video_format_id = vf.id
+		SET vf.formats = REPLACE(vf.formats, ?, ?)
+		WHERE fjvf.file_id = ?
+	`
+	if _, err = tx.Exec(query, oldLinkPrefix, newLinkPrefix, filesID); err != nil {
+		return fmt.Errorf("failed to update video formats: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) GetVideoByID(id int) (*models.InfoVideosResp, error) {
+	query := `
+	SELECT id, filename, status, is_stream, filepath, status_ai, COALESCE(container, '')
+	FROM files
+	WHERE id = ?
+`
+	row := s.db.QueryRow(query, id)
+
+	var videoInfo models.InfoVideosResp
+	if err := row.Scan(&videoInfo.Id, &videoInfo.FileName, &videoInfo.Status, &videoInfo.IsStream, &videoInfo.FilePath, &videoInfo.StatusAI, &videoInfo.Container); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVideoNotFound
+		}
+		return nil, err
+	}
+	return &videoInfo, nil
+}
+
+func (s *Storage) GetVideoFormatsByFileID(fileID int) ([]models.VideoFormat, error) {
+	query := `
+	SELECT vf.formats
+	FROM files_j_video_formats fjvf
+	INNER JOIN video_formats vf ON fjvf.video_format_id = vf.id
+	WHERE fjvf.file_id = ?
+`
+	rows, err := s.db.Query(query, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.VideoFormat
+	for rows.Next() {
+		var formatsJSON string
+		if err = rows.Scan(&formatsJSON); err != nil {
+			return nil, err
+		}
+		var videoFormats []models.VideoFormat
+		if err = json.Unmarshal([]byte(formatsJSON), &videoFormats); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal formats JSON: %w", err)
+		}
+		results = append(results, videoFormats...)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ReplaceVideoFormats swaps the renditions linked to a file for the given ones and marks the file done.
+func (s *Storage) ReplaceVideoFormats(fileID int, formats []models.VideoFormat) error {
+	formatsJSON, err := json.Marshal(formats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal formats JSON: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status models.FileStatus
+	var userID int
+	var isStream bool
+	query := `SELECT status, user_id, is_stream FROM files WHERE id = ? FOR UPDATE`
+	if err = tx.QueryRow(query, fileID).Scan(&status, &userID, &isStream); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVideoNotFound
+		}
+		return err
+	}
+	// A done file may have its renditions replaced without changing status.
+	if status != models.StatusDone && !status.CanTransitionTo(models.StatusDone) {
+		return &TransitionError{Current: status, Next: models.StatusDone}
+	}
+
+	if err = deleteVideoFormats(tx, fileID); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`INSERT INTO video_formats (formats) VALUES (?)`, string(formatsJSON))
+	if err != nil {
+		return fmt.Errorf("failed to insert video formats: %w", err)
+	}
+	formatID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`INSERT INTO files_j_video_formats (file_id, video_format_id) VALUES (?, ?)`, fileID, formatID); err != nil {
+		return fmt.Errorf("failed to link video formats: %w", err)
+	}
+	if status != models.StatusDone {
+		change := models.StatusChange{Actor: models.ActorConverter, Reason: "renditions registered"}
+		if err = transitionStatus(tx, fileID, 0, models.StatusDone, change, ", last_error = NULL"); err != nil {
+			return err
+		}
+	}
+	event := &models.FileEvent{
+		FileId:   fileID,
+		UserId:   userID,
+		Status:   models.StatusDone,
+		Actor:    models.ActorConverter,
+		IsStream: isStream,
+		Formats:  formats,
+	}
+	if err = writeFileEvent(tx, models.OutboxRenditionsRegistered, event); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteVideoFormats unlinks the file's formats first so the video_formats rows are free of foreign key references.
+func deleteVideoFormats(tx *sql.Tx, fileID int) error {
+	rows, err := tx.Query(`SELECT video_format_id FROM files_j_video_formats WHERE file_id = ?`, fileID)
+	if err != nil {
+		return err
+	}
+	var formatIDs []any
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		formatIDs = append(formatIDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(formatIDs) == 0 {
+		return nil
+	}
+
+	if _, err = tx.Exec(`DELETE FROM files_j_video_formats WHERE file_id = ?`, fileID); err != nil {
+		return fmt.Errorf("failed to unlink video formats: %w", err)
+	}
+	query := `DELETE FROM video_formats WHERE id IN (?` + strings.Repeat(", ?", len(formatIDs)-1) + `)`
+	if _, err = tx.Exec(query, formatIDs...); err != nil {
+		return fmt.Errorf("failed to delete video formats: %w", err)
+	}
+	return nil
+}
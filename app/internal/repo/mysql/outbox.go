@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"strings"
+	"time"
+)
+
+const outboxAggregateFile = "file"
+
+// writeFileEvent adds an event about a file to the outbox in the caller's transaction, so it is
+// published if and only if the change it describes is committed.
+func writeFileEvent(tx *sql.Tx, eventType string, event *models.FileEvent) error {
+	event.OccurredAt = time.Now().UTC()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox_events (aggregate_type, aggregate_id, event_type, payload)
+		VALUES (?, ?, ?, ?)
+	`
+	if _, err = tx.Exec(query, outboxAggregateFile, event.FileId, eventType, string(payload)); err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimOutboxEvents picks unpublished events in id order and pushes their next attempt leaseSeconds into
+// the future, so a second relay only takes them over if this one dies before recording the outcome.
+func (s *Storage) ClaimOutboxEvents(limit, leaseSeconds int) ([]*models.OutboxEvent, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT id, aggregate_type, aggregate_id, event_type, payload, attempts
+	FROM outbox_events
+	WHERE published_at IS NULL
+	AND next_attempt_at <= CURRENT_TIMESTAMP(3)
+	ORDER BY id
+	LIMIT ?
+	FOR UPDATE SKIP LOCKED
+`
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	var events []*models.OutboxEvent
+	var ids []any
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err = rows.Scan(&event.Id, &event.AggregateType, &event.AggregateId, &event.EventType, &event.Payload, &event.Attempts); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		events = append(events, &event)
+		ids = append(ids, event.Id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query = `UPDATE outbox_events SET next_attempt_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND WHERE id IN (?` +
+		strings.Repeat(", ?", len(ids)-1) + `)`
+	if _, err = tx.Exec(query, append([]any{leaseSeconds}, ids...)...); err != nil {
+		return nil, fmt.Errorf("failed to lease outbox events: %w", err)
+	}
+	return events, tx.Commit()
+}
+
+func (s *Storage) MarkOutboxEventPublished(id int64) error {
+	query := `
+		UPDATE outbox_events
+		SET published_at = CURRENT_TIMESTAMP(3), attempts = attempts + 1, last_error = NULL
+		WHERE id = ?
+	`
+	if _, err := s.db.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to mark outbox event published: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetOutboxEventError(id int64, lastError string, retryDelaySeconds int) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = ?, next_attempt_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND
+		WHERE id = ?
+	`
+	if _, err := s.db.Exec(query, lastError, retryDelaySeconds, id); err != nil {
+		return fmt.Errorf("failed to record outbox error: %w", err)
+	}
+	return nil
+}
+
+// DelayOutboxEvents moves the next attempt of claimed events without counting an attempt.
+func (s *Storage) DelayOutboxEvents(ids []int64, delaySeconds int) error {
+	args := []any{delaySeconds}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `UPDATE outbox_events SET next_attempt_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND WHERE id IN (?` +
+		strings.Repeat(", ?", len(ids)-1) + `) AND published_at IS NULL`
+	if _, err := s.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to delay outbox events: %w", err)
+	}
+	return nil
+}
+
+// DeletePublishedOutboxEvents drops events published more than retentionSeconds ago.
+func (s *Storage) DeletePublishedOutboxEvents(retentionSeconds int) (int64, error) {
+	query := `
+		DELETE FROM outbox_events
+		WHERE published_at IS NOT NULL
+		AND published_at < CURRENT_TIMESTAMP(3) - INTERVAL ? SECOND
+		LIMIT 10000
+	`
+	result, err := s.db.Exec(query, retentionSeconds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,322 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/go-sql-driver/mysql"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPlaylistNotFound     = errors.New("playlist not found")
+	ErrPlaylistItemNotFound = errors.New("playlist item not found")
+	ErrPlaylistItemExists   = errors.New("video is already in the playlist")
+	ErrPlaylistFull         = errors.New("playlist is full")
+	ErrPlaylistOrder        = errors.New("order must list every item of the playlist exactly once")
+)
+
+const playlistColumns = `p.id, p.user_id, p.title, COALESCE(p.description, ''), p.visibility, p.share_token,
+	(SELECT COUNT(*) FROM playlist_items WHERE playlist_id = p.id),
+	CAST(UNIX_TIMESTAMP(p.created_at) * 1000 AS SIGNED), CAST(UNIX_TIMESTAMP(p.updated_at) * 1000 AS SIGNED)`
+
+func (s *Storage) CountPlaylists(userID int) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM playlists WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+func (s *Storage) CreatePlaylist(playlist *models.Playlist) error {
+	query := `
+		INSERT INTO playlists (user_id, title, description, visibility, share_token)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?)
+	`
+	result, err := s.db.Exec(query, playlist.UserId, playlist.Title, playlist.Description, playlist.Visibility, playlist.ShareToken)
+	if err != nil {
+		return fmt.Errorf("failed to create playlist: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	playlist.Id = int(id)
+	playlist.CreatedAt = time.Now().UTC()
+	playlist.UpdatedAt = playlist.CreatedAt
+	return nil
+}
+
+func (s *Storage) GetPlaylists(userID int) ([]*models.Playlist, error) {
+	rows, err := s.db.Query(`SELECT `+playlistColumns+` FROM playlists p WHERE p.user_id = ? ORDER BY p.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	playlists := []*models.Playlist{}
+	for rows.Next() {
+		playlist, err := scanPlaylist(rows)
+		if err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, playlist)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+// GetPlaylist returns the user's playlist with its items in order.
+func (s *Storage) GetPlaylist(id, userID int) (*models.Playlist, error) {
+	row := s.db.QueryRow(`SELECT `+playlistColumns+` FROM playlists p WHERE p.id = ? AND p.user_id = ?`, id, userID)
+	return s.playlistWithItems(row)
+}
+
+// GetSharedPlaylist finds a playlist by share token among unlisted and public playlists, or by id among
+// public ones when id is positive.
+func (s *Storage) GetSharedPlaylist(token string, id int) (*models.Playlist, error) {
+	query := `SELECT ` + playlistColumns + ` FROM playlists p
+	WHERE (p.share_token = ? AND p.visibility IN ('unlisted', 'public'))
+	OR (p.id = ? AND p.visibility = 'public')
+	LIMIT 1`
+	return s.playlistWithItems(s.db.QueryRow(query, token, id))
+}
+
+func (s *Storage) playlistWithItems(row *sql.Row) (*models.Playlist, error) {
+	playlist, err := scanPlaylist(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPlaylistNotFound
+		}
+		return nil, err
+	}
+	if playlist.Items, err = s.getPlaylistItems(playlist.Id); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+func scanPlaylist(row rowScanner) (*models.Playlist, error) {
+	var playlist models.Playlist
+	var createdMs, updatedMs int64
+	if err := row.Scan(&playlist.Id, &playlist.UserId, &playlist.Title, &playlist.Description, &playlist.Visibility,
+		&playlist.ShareToken, &playlist.ItemCount, &createdMs, &updatedMs); err != nil {
+		return nil, err
+	}
+	playlist.CreatedAt = time.UnixMilli(createdMs).UTC()
+	playlist.UpdatedAt = time.UnixMilli(updatedMs).UTC()
+	return &playlist, nil
+}
+
+func (s *Storage) getPlaylistItems(playlistID int) ([]*models.PlaylistItem, error) {
+	query := `
+	SELECT i.id, i.file_id, i.position, f.filename, COALESCE(f.title, ''), f.status
+	FROM playlist_items i
+	INNER JOIN files f ON f.id = i.file_id
+	WHERE i.playlist_id = ?
+	ORDER BY i.position
+`
+	rows, err := s.db.Query(query, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*models.PlaylistItem{}
+	for rows.Next() {
+		var item models.PlaylistItem
+		if err = rows.Scan(&item.Id, &item.FileId, &item.Position, &item.FileName, &item.Title, &item.Status); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *Storage) UpdatePlaylist(playlist *models.Playlist) error {
+	query := `
+		UPDATE playlists
+		SET title = ?, description = NULLIF(?, ''), visibility = ?, share_token = ?
+		WHERE id = ? AND user_id = ?
+	`
+	_, err := s.db.Exec(query, playlist.Title, playlist.Description, playlist.Visibility, playlist.ShareToken, playlist.Id, playlist.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to update playlist: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeletePlaylist(id, userID int) error {
+	result, err := s.db.Exec(`DELETE FROM playlists WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrPlaylistNotFound
+	}
+	return nil
+}
+
+// AddPlaylistItems inserts the user's videos at position, or appends them when position is nil, keeping
+// positions contiguous from zero.
+func (s *Storage) AddPlaylistItems(playlistID, userID int, fileIDs []int, position *int, maxItems int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	count, err := lockPlaylist(tx, playlistID, userID)
+	if err != nil {
+		return err
+	}
+	if count+len(fileIDs) > maxItems {
+		return fmt.Errorf("%w: at most %d items", ErrPlaylistFull, maxItems)
+	}
+
+	args := []any{userID}
+	for _, id := range fileIDs {
+		args = append(args, id)
+	}
+	query := `SELECT id FROM files WHERE user_id = ? AND status <> 'deleted' AND id IN (?` +
+		strings.Repeat(", ?", len(fileIDs)-1) + `) FOR SHARE`
+	found, err := queryIDs(tx, query, args...)
+	if err != nil {
+		return err
+	}
+	if len(found) != len(fileIDs) {
+		return ErrVideoNotFound
+	}
+
+	at := count
+	if position != nil && *position < count {
+		at = *position
+		query = `UPDATE playlist_items SET position = position + ? WHERE playlist_id = ? AND position >= ?`
+		if _, err = tx.Exec(query, len(fileIDs), playlistID, at); err != nil {
+			return err
+		}
+	}
+	for i, fileID := range fileIDs {
+		query = `INSERT INTO playlist_items (playlist_id, file_id, position) VALUES (?, ?, ?)`
+		if _, err = tx.Exec(query, playlistID, fileID, at+i); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+				return fmt.Errorf("%w: video %d", ErrPlaylistItemExists, fileID)
+			}
+			return err
+		}
+	}
+	if err = touchPlaylist(tx, playlistID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemovePlaylistItem deletes the item and closes the gap it leaves.
+func (s *Storage) RemovePlaylistItem(playlistID, userID int, itemID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = lockPlaylist(tx, playlistID, userID); err != nil {
+		return err
+	}
+	var position int
+	query := `SELECT position FROM playlist_items WHERE id = ? AND playlist_id = ?`
+	if err = tx.QueryRow(query, itemID, playlistID).Scan(&position); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlaylistItemNotFound
+		}
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM playlist_items WHERE id = ?`, itemID); err != nil {
+		return err
+	}
+	query = `UPDATE playlist_items SET position = position - 1 WHERE playlist_id = ? AND position > ?`
+	if _, err = tx.Exec(query, playlistID, position); err != nil {
+		return err
+	}
+	if err = touchPlaylist(tx, playlistID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderPlaylistItems gives every item its index in itemIDs as position.
+func (s *Storage) ReorderPlaylistItems(playlistID, userID int, itemIDs []int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	count, err := lockPlaylist(tx, playlistID, userID)
+	if err != nil {
+		return err
+	}
+	if count != len(itemIDs) {
+		return ErrPlaylistOrder
+	}
+	rows, err := tx.Query(`SELECT id FROM playlist_items WHERE playlist_id = ?`, playlistID)
+	if err != nil {
+		return err
+	}
+	var current []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range itemIDs {
+		if !slices.Contains(current, id) {
+			return ErrPlaylistOrder
+		}
+	}
+
+	for position, id := range itemIDs {
+		if _, err = tx.Exec(`UPDATE playlist_items SET position = ? WHERE id = ?`, position, id); err != nil {
+			return err
+		}
+	}
+	if err = touchPlaylist(tx, playlistID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockPlaylist locks the user's playlist against concurrent item changes and returns its item count.
+func lockPlaylist(tx *sql.Tx, playlistID, userID int) (int, error) {
+	var locked int
+	err := tx.QueryRow(`SELECT id FROM playlists WHERE id = ? AND user_id = ? FOR UPDATE`, playlistID, userID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrPlaylistNotFound
+		}
+		return 0, err
+	}
+	var count int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM playlist_items WHERE playlist_id = ?`, playlistID).Scan(&count)
+	return count, err
+}
+
+func touchPlaylist(tx *sql.Tx, playlistID int) error {
+	_, err := tx.Exec(`UPDATE playlists SET updated_at = CURRENT_TIMESTAMP(3) WHERE id = ?`, playlistID)
+	return err
+}
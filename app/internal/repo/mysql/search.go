@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"strings"
+)
+
+// GetSearchDocument assembles the searchable fields of a file from their sources.
+func (s *Storage) GetSearchDocument(fileID int) (*models.SearchDocument, models.FileStatus, error) {
+	query := `
+	SELECT f.user_id, f.filename, f.status, COALESCE(f.title, ''), COALESCE(f.description, ''), f.tags,
+		COALESCE(t.text, '')
+	FROM files f
+	LEFT JOIN video_transcripts t ON t.file_id = f.id
+	WHERE f.id = ?
+`
+	doc := &models.SearchDocument{FileId: fileID}
+	var status models.FileStatus
+	var tags string
+	err := s.db.QueryRow(query, fileID).Scan(&doc.UserId, &doc.FileName, &status, &doc.Title, &doc.Description, &tags, &doc.Transcript)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrVideoNotFound
+		}
+		return nil, "", err
+	}
+	if tags != "" {
+		doc.Tags = splitTags(tags)
+	}
+	return doc, status, nil
+}
+
+func (s *Storage) SetTranscript(fileID int, language, text string) error {
+	query := `
+		INSERT INTO video_transcripts (file_id, language, text)
+		SELECT id, NULLIF(?, ''), ? FROM files WHERE id = ? AND status <> 'deleted'
+		ON DUPLICATE KEY UPDATE language = VALUES(language), text = VALUES(text)
+	`
+	result, err := s.db.Exec(query, language, text, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to store transcript: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrVideoNotFound
+	}
+	return nil
+}
+
+func (s *Storage) UpsertSearchDocument(doc *models.SearchDocument) error {
+	query := `
+		INSERT INTO video_search (file_id, user_id, filename, title, description, tags, transcript)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), filename = VALUES(filename), title = VALUES(title),
+			description = VALUES(description), tags = VALUES(tags), transcript = VALUES(transcript)
+	`
+	_, err := s.db.Exec(query, doc.FileId, doc.UserId, doc.FileName, doc.Title, doc.Description,
+		strings.Join(doc.Tags, "\n"), doc.Transcript)
+	if err != nil {
+		return fmt.Errorf("failed to index video: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteSearchDocument(fileID int) error {
+	if _, err := s.db.Exec(`DELETE FROM video_search WHERE file_id = ?`, fileID); err != nil {
+		return fmt.Errorf("failed to remove video from index: %w", err)
+	}
+	return nil
+}
+
+// SearchDocuments runs a boolean mode full-text query over the user's documents. Matches in the file name
+// and title count twice, on top of their weight in the query over all fields.
+func (s *Storage) SearchDocuments(userID int, booleanQuery string, limit, offset int) ([]*models.SearchDocument, []float64, int, error) {
+	const all = `MATCH (filename, title, description, tags, transcript) AGAINST (? IN BOOLEAN MODE)`
+	const name = `MATCH (filename, title) AGAINST (? IN BOOLEAN MODE)`
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM video_search WHERE user_id = ? AND `+all, userID, booleanQuery).Scan(&total); err != nil {
+		return nil, nil, 0, err
+	}
+	if total == 0 {
+		return nil, nil, 0, nil
+	}
+
+	query := `
+	SELECT file_id, user_id, filename, title, description, tags, transcript, ` + all + ` + 2 * ` + name + ` AS score
+	FROM video_search
+	WHERE user_id = ?
+	AND ` + all + `
+	ORDER BY score DESC, file_id DESC
+	LIMIT ? OFFSET ?
+`
+	rows, err := s.db.Query(query, booleanQuery, booleanQuery, userID, booleanQuery, limit, offset)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer rows.Close()
+
+	var docs []*models.SearchDocument
+	var scores []float64
+	for rows.Next() {
+		var doc models.SearchDocument
+		var tags string
+		var score float64
+		if err = rows.Scan(&doc.FileId, &doc.UserId, &doc.FileName, &doc.Title, &doc.Description, &tags, &doc.Transcript, &score); err != nil {
+			return nil, nil, 0, err
+		}
+		if tags != "" {
+			doc.Tags = strings.Split(tags, "\n")
+		}
+		docs = append(docs, &doc)
+		scores = append(scores, score)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+	return docs, scores, total, nil
+}
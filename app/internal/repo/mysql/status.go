@@ -0,0 +1,170 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"time"
+)
+
+var ErrIllegalTransition = errors.New("illegal status transition")
+
+// TransitionError reports the status a file was in when a transition was refused.
+type TransitionError struct {
+	Current models.FileStatus
+	Next    models.FileStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%v from %s to %s", ErrIllegalTransition, e.Current, e.Next)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
+
+// transitionStatus moves a file to next if the transition table allows it and records the change in
+// file_status_history within the same transaction. The row is locked and the UPDATE is conditional on the
+// status that was read, so concurrent writers cannot race past the table. extraSet and extraArgs are applied
+// in the same statement; userID limits the update to the owner when positive. The change is also written to
+// the outbox for the broker relay.
+func transitionStatus(tx *sql.Tx, fileID, userID int, next models.FileStatus, change models.StatusChange, extraSet string, extraArgs ...any) error {
+	query := `SELECT status, user_id, is_stream FROM files WHERE id = ?`
+	args := []any{fileID}
+	if userID > 0 {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	var current models.FileStatus
+	var ownerID int
+	var isStream bool
+	if err := tx.QueryRow(query+` FOR UPDATE`, args...).Scan(&current, &ownerID, &isStream); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVideoNotFound
+		}
+		return err
+	}
+	if !current.CanTransitionTo(next) {
+		return &TransitionError{Current: current, Next: next}
+	}
+
+	query = `UPDATE files SET status = ?` + extraSet + ` WHERE id = ? AND status = ?`
+	args = append(append([]any{next}, extraArgs...), fileID, current)
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return &TransitionError{Current: current, Next: next}
+	}
+
+	if err = recordStatusChange(tx, fileID, current, next, change); err != nil {
+		return err
+	}
+	if err = enqueueWebhookEvent(tx, fileID, current, next, change); err != nil {
+		return err
+	}
+	return writeFileEvent(tx, models.OutboxFileStatusChanged, &models.FileEvent{
+		FileId:    fileID,
+		UserId:    ownerID,
+		Status:    next,
+		OldStatus: current,
+		Actor:     change.Actor,
+		Reason:    change.Reason,
+		IsStream:  isStream,
+	})
+}
+
+func recordStatusChange(tx *sql.Tx, fileID int, old, next models.FileStatus, change models.StatusChange) error {
+	query := `
+		INSERT INTO file_status_history (file_id, old_status, new_status, actor, reason)
+		VALUES (?, NULLIF(?, ''), ?, ?, NULLIF(?, ''))
+	`
+	if _, err := tx.Exec(query, fileID, old, next, change.Actor, change.Reason); err != nil {
+		return fmt.Errorf("failed to record status change: %w", err)
+	}
+	return nil
+}
+
+// GetStatusHistory returns the file's status changes, oldest first.
+func (s *Storage) GetStatusHistory(fileID int) ([]*models.StatusHistoryEntry, error) {
+	query := `
+	SELECT id, COALESCE(old_status, ''), new_status, actor, COALESCE(reason, ''),
+		CAST(UNIX_TIMESTAMP(created_at) * 1000 AS SIGNED)
+	FROM file_status_history
+	WHERE file_id = ?
+	ORDER BY id
+`
+	rows, err := s.db.Query(query, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.StatusHistoryEntry{}
+	for rows.Next() {
+		var entry models.StatusHistoryEntry
+		var createdMs int64
+		if err = rows.Scan(&entry.Id, &entry.OldStatus, &entry.NewStatus, &entry.Actor, &entry.Reason, &createdMs); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = time.UnixMilli(createdMs).UTC()
+		results = append(results, &entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetUserStatusHistorySince returns status changes of all the user's files after the given history id.
+// Ids are taken at insert but become visible at commit, so a row written less than commitLag ago may
+// still be followed by a lower id. The result ends before the first such row, letting callers use the
+// last returned id as a cursor without skipping late commits.
+func (s *Storage) GetUserStatusHistorySince(userID int, afterID int64, limit int, commitLag time.Duration) ([]*models.StatusHistoryEntry, error) {
+	query := `
+	SELECT h.id, h.file_id, COALESCE(h.old_status, ''), h.new_status, h.actor, COALESCE(h.reason, ''),
+		CAST(UNIX_TIMESTAMP(h.created_at) * 1000 AS SIGNED), h.created_at < NOW(3) - INTERVAL ? MICROSECOND
+	FROM file_status_history h
+	INNER JOIN files f ON f.id = h.file_id
+	WHERE f.user_id = ?
+	AND h.id > ?
+	ORDER BY h.id
+	LIMIT ?
+`
+	rows, err := s.db.Query(query, commitLag.Microseconds(), userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.StatusHistoryEntry
+	for rows.Next() {
+		var entry models.StatusHistoryEntry
+		var createdMs int64
+		var settled bool
+		if err = rows.Scan(&entry.Id, &entry.FileId, &entry.OldStatus, &entry.NewStatus, &entry.Actor, &entry.Reason, &createdMs, &settled); err != nil {
+			return nil, err
+		}
+		if !settled {
+			break
+		}
+		entry.CreatedAt = time.UnixMilli(createdMs).UTC()
+		results = append(results, &entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetLatestStatusHistoryID returns the newest id older than commitLag, below which no row can still commit.
+func (s *Storage) GetLatestStatusHistoryID(commitLag time.Duration) (int64, error) {
+	var id int64
+	query := `SELECT COALESCE(MAX(id), 0) FROM file_status_history WHERE created_at < NOW(3) - INTERVAL ? MICROSECOND`
+	err := s.db.QueryRow(query, commitLag.Microseconds()).Scan(&id)
+	return id, err
+}
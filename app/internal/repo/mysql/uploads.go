@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"time"
+)
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+func (s *Storage) CreateUpload(upload *models.Upload) error {
+	query := `
+		INSERT INTO uploads (id, user_id, filename, filepath, is_stream, upload_length)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(query, upload.Id, upload.UserId, upload.FileName, upload.FilePath, upload.IsStream, upload.Length)
+	if err != nil {
+		return fmt.Errorf("failed to insert upload: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetUpload(id string, userID int) (*models.Upload, error) {
+	query := `
+	SELECT id, user_id, filename, filepath, is_stream, upload_length, upload_offset, COALESCE(file_id, 0),
+		CAST(UNIX_TIMESTAMP(updated_at) * 1000 AS SIGNED)
+	FROM uploads
+	WHERE id = ?
+	AND user_id = ?
+`
+	row := s.db.QueryRow(query, id, userID)
+
+	var upload models.Upload
+	var updatedMs int64
+	if err := row.Scan(&upload.Id, &upload.UserId, &upload.FileName, &upload.FilePath, &upload.IsStream, &upload.Length, &upload.Offset, &upload.FileId, &updatedMs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	upload.UpdatedAt = time.UnixMilli(updatedMs).UTC()
+	return &upload, nil
+}
+
+func (s *Storage) SetUploadOffset(id string, offset int64) error {
+	query := `
+		UPDATE uploads
+		SET upload_offset = ?
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query, offset, id)
+	if err != nil {
+		return fmt.Errorf("failed to update upload offset: %w", err)
+	}
+	return nil
+}
+
+// CompleteUpload registers the uploaded file and links the upload to it in one transaction, so an
+// upload is never registered twice, not even when its completion is retried.
+func (s *Storage) CompleteUpload(upload *models.Upload) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	fileID, err := insertFile(tx, upload.FileName, upload.FilePath, upload.IsStream, upload.UserId)
+	if err != nil {
+		return 0, err
+	}
+	query := `
+		UPDATE uploads
+		SET file_id = ?
+		WHERE id = ?
+		AND file_id IS NULL
+	`
+	result, err := tx.Exec(query, fileID, upload.Id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update upload file id: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if affected == 0 {
+		return 0, ErrUploadNotFound
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return fileID, nil
+}
+
+// GetExpiredUploads returns the unfinished uploads that saw no progress since before.
+func (s *Storage) GetExpiredUploads(before time.Time) ([]string, error) {
+	query := `
+	SELECT id
+	FROM uploads
+	WHERE file_id IS NULL
+	AND updated_at < FROM_UNIXTIME(? / 1000)
+`
+	rows, err := s.db.Query(query, before.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteExpiredUpload deletes the upload if it is still unfinished and idle since before, and reports
+// whether it did.
+func (s *Storage) DeleteExpiredUpload(id string, before time.Time) (bool, error) {
+	query := `
+		DELETE FROM uploads
+		WHERE id = ?
+		AND file_id IS NULL
+		AND updated_at < FROM_UNIXTIME(? / 1000)
+	`
+	result, err := s.db.Exec(query, id, before.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("failed to delete expired upload: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *Storage) DeleteUpload(id string, userID int) error {
+	query := `
+		DELETE FROM uploads
+		WHERE id = ?
+		AND user_id = ?
+	`
+	_, err := s.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
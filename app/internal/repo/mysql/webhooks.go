@@ -0,0 +1,289 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+func (s *Storage) CreateWebhook(hook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, events, active)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	result, err := s.db.Exec(query, hook.UserId, hook.URL, hook.Secret, strings.Join(hook.Events, ","), hook.Active)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	hook.Id = int(id)
+	hook.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+func (s *Storage) CountWebhooks(userID int) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+func (s *Storage) GetWebhooks(userID int) ([]*models.Webhook, error) {
+	query := `
+	SELECT id, user_id, url, secret, events, active, CAST(UNIX_TIMESTAMP(created_at) * 1000 AS SIGNED)
+	FROM webhooks
+	WHERE user_id = ?
+	ORDER BY id
+`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, hook)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *Storage) GetWebhook(id, userID int) (*models.Webhook, error) {
+	query := `
+	SELECT id, user_id, url, secret, events, active, CAST(UNIX_TIMESTAMP(created_at) * 1000 AS SIGNED)
+	FROM webhooks
+	WHERE id = ?
+	AND user_id = ?
+`
+	hook, err := scanWebhook(s.db.QueryRow(query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	return hook, err
+}
+
+func (s *Storage) UpdateWebhook(hook *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = ?, secret = ?, events = ?, active = ?
+		WHERE id = ?
+		AND user_id = ?
+	`
+	_, err := s.db.Exec(query, hook.URL, hook.Secret, strings.Join(hook.Events, ","), hook.Active, hook.Id, hook.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteWebhook(id, userID int) error {
+	result, err := s.db.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns the newest deliveries of a webhook first.
+func (s *Storage) GetWebhookDeliveries(webhookID, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+	SELECT id, webhook_id, event, file_id, payload, status, attempts, COALESCE(response_status, 0),
+		COALESCE(last_error, ''), CAST(UNIX_TIMESTAMP(created_at) * 1000 AS SIGNED),
+		COALESCE(CAST(UNIX_TIMESTAMP(delivered_at) * 1000 AS SIGNED), 0)
+	FROM webhook_deliveries
+	WHERE webhook_id = ?
+	ORDER BY id DESC
+	LIMIT ?
+`
+	rows, err := s.db.Query(query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var createdMs, deliveredMs int64
+		if err = rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.Event, &delivery.FileId, &delivery.Payload,
+			&delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError, &createdMs, &deliveredMs); err != nil {
+			return nil, err
+		}
+		delivery.CreatedAt = time.UnixMilli(createdMs).UTC()
+		if deliveredMs > 0 {
+			deliveredAt := time.UnixMilli(deliveredMs).UTC()
+			delivery.DeliveredAt = &deliveredAt
+		}
+		results = append(results, &delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// RedeliverWebhookDelivery queues a copy of an earlier delivery so the original stays in the log.
+func (s *Storage) RedeliverWebhookDelivery(webhookID int, deliveryID int64) (int64, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, file_id, payload)
+		SELECT webhook_id, event, file_id, payload
+		FROM webhook_deliveries
+		WHERE id = ?
+		AND webhook_id = ?
+	`
+	result, err := s.db.Exec(query, deliveryID, webhookID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if affected == 0 {
+		return 0, ErrDeliveryNotFound
+	}
+	return result.LastInsertId()
+}
+
+// ClaimWebhookDeliveries picks due deliveries and pushes their next attempt leaseSeconds into the future,
+// so another dispatcher only picks them up again if this one dies before recording a result.
+func (s *Storage) ClaimWebhookDeliveries(limit, leaseSeconds int) ([]*models.WebhookDelivery, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT d.id, d.webhook_id, d.event, d.file_id, d.payload, d.attempts, w.url, w.secret
+	FROM webhook_deliveries d
+	INNER JOIN webhooks w ON w.id = d.webhook_id
+	WHERE d.status = 'pending'
+	AND d.next_attempt_at <= CURRENT_TIMESTAMP(3)
+	AND w.active = 1
+	ORDER BY d.next_attempt_at, d.id
+	LIMIT ?
+	FOR UPDATE OF d SKIP LOCKED
+`
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []*models.WebhookDelivery
+	var ids []any
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err = rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.Event, &delivery.FileId, &delivery.Payload,
+			&delivery.Attempts, &delivery.URL, &delivery.Secret); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deliveries = append(deliveries, &delivery)
+		ids = append(ids, delivery.Id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query = `UPDATE webhook_deliveries SET next_attempt_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND WHERE id IN (?` +
+		strings.Repeat(", ?", len(ids)-1) + `)`
+	if _, err = tx.Exec(query, append([]any{leaseSeconds}, ids...)...); err != nil {
+		return nil, fmt.Errorf("failed to lease webhook deliveries: %w", err)
+	}
+	return deliveries, tx.Commit()
+}
+
+// SetWebhookDeliveryResult records an attempt; a pending delivery is retried after retryDelaySeconds.
+func (s *Storage) SetWebhookDeliveryResult(id int64, status models.WebhookDeliveryStatus, responseStatus int, lastError string, retryDelaySeconds int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, response_status = NULLIF(?, 0), last_error = NULLIF(?, ''),
+			next_attempt_at = CURRENT_TIMESTAMP(3) + INTERVAL ? SECOND,
+			delivered_at = IF(? = 'succeeded', CURRENT_TIMESTAMP(3), NULL)
+		WHERE id = ?
+	`
+	if _, err := s.db.Exec(query, status, responseStatus, lastError, retryDelaySeconds, status, id); err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// enqueueWebhookEvent queues a delivery of the status change to every matching webhook of the file's owner
+// in the caller's transaction, so an event is only announced if the change is committed.
+func enqueueWebhookEvent(tx *sql.Tx, fileID int, old, next models.FileStatus, change models.StatusChange) error {
+	event, ok := models.EventForStatus(next)
+	if !ok {
+		return nil
+	}
+
+	var payload models.WebhookPayload
+	payload.Event = event
+	payload.OccurredAt = time.Now().UTC()
+	payload.Data.FileId = fileID
+	payload.Data.Status = next
+	payload.Data.OldStatus = old
+	payload.Data.Actor = change.Actor
+	payload.Data.Reason = change.Reason
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, file_id, payload)
+		SELECT w.id, ?, f.id, ?
+		FROM webhooks w
+		INNER JOIN files f ON f.user_id = w.user_id
+		WHERE f.id = ?
+		AND w.active = 1
+		AND (w.events = '' OR FIND_IN_SET(?, w.events))
+	`
+	if _, err = tx.Exec(query, event, string(body), fileID, event); err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var hook models.Webhook
+	var events string
+	var createdMs int64
+	if err := row.Scan(&hook.Id, &hook.UserId, &hook.URL, &hook.Secret, &events, &hook.Active, &createdMs); err != nil {
+		return nil, err
+	}
+	hook.Events = []string{}
+	if events != "" {
+		hook.Events = strings.Split(events, ",")
+	}
+	hook.CreatedAt = time.UnixMilli(createdMs).UTC()
+	return &hook, nil
+}
@@ -0,0 +1,129 @@
+package search
+
+import (
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	fragmentContext   = 60
+	maxFragments      = 3
+	highlightOpenTag  = "<mark>"
+	highlightCloseTag = "</mark>"
+)
+
+type span struct {
+	start, end int
+}
+
+// Highlight returns, per matching field, up to three HTML escaped fragments with the words matched by
+// the terms wrapped in <mark>. Fields are keyed by their JSON names.
+func Highlight(doc *models.SearchDocument, terms []string) map[string][]string {
+	highlights := make(map[string][]string)
+	fields := []struct {
+		name string
+		text string
+	}{
+		{"file_name", doc.FileName},
+		{"title", doc.Title},
+		{"description", doc.Description},
+		{"transcript", doc.Transcript},
+	}
+	for _, field := range fields {
+		if fragments := highlightText(field.text, terms); len(fragments) > 0 {
+			highlights[field.name] = fragments
+		}
+	}
+	for _, tag := range doc.Tags {
+		if fragments := highlightText(tag, terms); len(fragments) > 0 {
+			highlights["tags"] = append(highlights["tags"], fragments[0])
+		}
+	}
+	return highlights
+}
+
+func highlightText(text string, terms []string) []string {
+	matches := matchSpans(text, terms)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var fragments []string
+	for i := 0; i < len(matches) && len(fragments) < maxFragments; {
+		start := wordStart(text, max(matches[i].start-fragmentContext, 0))
+		end := wordEnd(text, min(matches[i].end+fragmentContext, len(text)))
+
+		var b strings.Builder
+		if start > 0 {
+			b.WriteString("…")
+		}
+		pos := start
+		for ; i < len(matches) && matches[i].end <= end; i++ {
+			b.WriteString(html.EscapeString(text[pos:matches[i].start]))
+			b.WriteString(highlightOpenTag + html.EscapeString(text[matches[i].start:matches[i].end]) + highlightCloseTag)
+			pos = matches[i].end
+		}
+		b.WriteString(html.EscapeString(text[pos:end]))
+		if end < len(text) {
+			b.WriteString("…")
+		}
+		fragments = append(fragments, b.String())
+	}
+	return fragments
+}
+
+// matchSpans returns the byte ranges of the words in text that a term is a prefix of, case-insensitively.
+func matchSpans(text string, terms []string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text + " " {
+		if !isSeparator(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			word := strings.ToLower(text[start:i])
+			for _, term := range terms {
+				if strings.HasPrefix(word, term) {
+					spans = append(spans, span{start, i})
+					break
+				}
+			}
+			start = -1
+		}
+	}
+	return spans
+}
+
+// wordStart moves back to the beginning of the word containing pos so fragments never cut words.
+func wordStart(text string, pos int) int {
+	for pos > 0 && pos < len(text) && !utf8.RuneStart(text[pos]) {
+		pos--
+	}
+	for pos > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:pos])
+		if isSeparator(r) {
+			break
+		}
+		pos -= size
+	}
+	return pos
+}
+
+func wordEnd(text string, pos int) int {
+	for pos < len(text) && !utf8.RuneStart(text[pos]) {
+		pos++
+	}
+	for pos < len(text) {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if isSeparator(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
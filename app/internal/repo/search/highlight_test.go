@@ -0,0 +1,87 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Dimoonevs/video-service/app/internal/models"
+)
+
+func TestHighlightText(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 20)
+
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  []string
+	}{
+		{"no match", "a quiet river", []string{"rome"}, nil},
+		{"whole word", "history of rome", []string{"rome"}, []string{"history of <mark>rome</mark>"}},
+		{"prefix marks the whole word", "Romans in Rome", []string{"rom"}, []string{"<mark>Romans</mark> in <mark>Rome</mark>"}},
+		{"term inside a word is no match", "metronome", []string{"nome"}, nil},
+		{"text is escaped", `<b>"rome" & co</b>`, []string{"rome"}, []string{`&lt;b&gt;&#34;<mark>rome</mark>&#34; &amp; co&lt;/b&gt;`}},
+		{"markup in the match is escaped", "rome<script>", []string{"rome"}, []string{"<mark>rome</mark>&lt;script&gt;"}},
+		{"several terms", "cats and dogs", []string{"dog", "cat"}, []string{"<mark>cats</mark> and <mark>dogs</mark>"}},
+		{
+			"context is cut at word boundaries",
+			long + "rome " + long,
+			[]string{"rome"},
+			[]string{"…lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum <mark>rome</mark> lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum…"},
+		},
+		{
+			"at most three fragments",
+			strings.Repeat("rome "+long, 5),
+			[]string{"rome"},
+			[]string{
+				"<mark>rome</mark> lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum…",
+				"…lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum <mark>rome</mark> lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum…",
+				"…lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum <mark>rome</mark> lorem ipsum lorem ipsum lorem ipsum lorem ipsum lorem ipsum…",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightText(tt.text, tt.terms); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("highlightText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightTextKeepsMultibyteRunes(t *testing.T) {
+	text := strings.Repeat("щ", 100) + " рим " + strings.Repeat("ж", 100)
+
+	fragments := highlightText(text, []string{"рим"})
+	if len(fragments) != 1 {
+		t.Fatalf("got %d fragments, want 1", len(fragments))
+	}
+	if !utf8.ValidString(fragments[0]) {
+		t.Errorf("fragment %q is not valid UTF-8", fragments[0])
+	}
+	if !strings.Contains(fragments[0], "<mark>рим</mark>") {
+		t.Errorf("fragment %q does not mark the match", fragments[0])
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	doc := &models.SearchDocument{
+		FileName:    "rome.mp4",
+		Title:       "History of Rome",
+		Description: "Nothing to see",
+		Tags:        []string{"ancient", "romans", "empire"},
+		Transcript:  "",
+	}
+
+	want := map[string][]string{
+		"file_name": {"<mark>rome</mark>.mp4"},
+		"title":     {"History of <mark>Rome</mark>"},
+		"tags":      {"<mark>romans</mark>"},
+	}
+	if got := Highlight(doc, []string{"rom"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Highlight() = %q, want %q", got, want)
+	}
+}
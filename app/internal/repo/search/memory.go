@@ -0,0 +1,97 @@
+package search
+
+import (
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is an in-process index for tests and local development. Scores count the words a term prefixes,
+// weighted by field.
+type Memory struct {
+	mu   sync.RWMutex
+	docs map[int]*models.SearchDocument
+}
+
+func NewMemory() *Memory {
+	return &Memory{docs: make(map[int]*models.SearchDocument)}
+}
+
+func (m *Memory) Index(doc *models.SearchDocument) error {
+	stored := *doc
+	stored.Tags = append([]string(nil), doc.Tags...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.docs[doc.FileId] = &stored
+	return nil
+}
+
+func (m *Memory) Delete(fileID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.docs, fileID)
+	return nil
+}
+
+func (m *Memory) Search(userID int, terms []string, limit, offset int) ([]*Result, int, error) {
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	m.mu.RLock()
+	var results []*Result
+	for _, doc := range m.docs {
+		if doc.UserId != userID {
+			continue
+		}
+		if score := memoryScore(doc, terms); score > 0 {
+			stored := *doc
+			results = append(results, &Result{Document: &stored, Score: score})
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Document.FileId > results[j].Document.FileId
+	})
+	total := len(results)
+	if offset >= total {
+		return nil, total, nil
+	}
+	return results[offset:min(offset+limit, total)], total, nil
+}
+
+// memoryScore is zero unless every term matches somewhere.
+func memoryScore(doc *models.SearchDocument, terms []string) float64 {
+	fields := []struct {
+		text   string
+		weight float64
+	}{
+		{doc.FileName, 3},
+		{doc.Title, 3},
+		{strings.Join(doc.Tags, " "), 2},
+		{doc.Description, 1},
+		{doc.Transcript, 1},
+	}
+
+	var score float64
+	for _, term := range terms {
+		var termScore float64
+		for _, field := range fields {
+			for _, word := range strings.FieldsFunc(strings.ToLower(field.text), isSeparator) {
+				if strings.HasPrefix(word, term) {
+					termScore += field.weight
+				}
+			}
+		}
+		if termScore == 0 {
+			return 0
+		}
+		score += termScore
+	}
+	return score
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Dimoonevs/video-service/app/internal/models"
+)
+
+func newTestMemory(t *testing.T) *Memory {
+	t.Helper()
+	m := NewMemory()
+	docs := []*models.SearchDocument{
+		{FileId: 1, UserId: 1, FileName: "a.mp4", Title: "Rome", Tags: []string{"history"}},
+		{FileId: 2, UserId: 1, FileName: "rome.mp4", Title: "Rome and romans"},
+		{FileId: 3, UserId: 1, FileName: "c.mp4", Description: "Filmed in rome"},
+		{FileId: 4, UserId: 2, FileName: "rome.mp4", Title: "Rome"},
+		{FileId: 5, UserId: 1, FileName: "e.mp4", Title: "Rome"},
+		{FileId: 6, UserId: 1, FileName: "f.mp4", Transcript: "metronome"},
+	}
+	for _, doc := range docs {
+		if err := m.Index(doc); err != nil {
+			t.Fatalf("Index(%d) error = %v", doc.FileId, err)
+		}
+	}
+	return m
+}
+
+func TestMemorySearch(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     int
+		terms      []string
+		limit      int
+		offset     int
+		wantIDs    []int
+		wantScores []float64
+		wantTotal  int
+	}{
+		{"weighted by field, ties by newest", 1, []string{"rom"}, 10, 0, []int{2, 5, 1, 3}, []float64{9, 3, 3, 1}, 4},
+		{"every term must match", 1, []string{"rom", "hist"}, 10, 0, []int{1}, []float64{5}, 1},
+		{"term inside a word is no match", 1, []string{"nome"}, 10, 0, nil, nil, 0},
+		{"other users are not searched", 2, []string{"rom"}, 10, 0, []int{4}, []float64{6}, 1},
+		{"no terms", 1, nil, 10, 0, nil, nil, 0},
+		{"limit", 1, []string{"rom"}, 2, 0, []int{2, 5}, []float64{9, 3}, 4},
+		{"offset", 1, []string{"rom"}, 2, 1, []int{5, 1}, []float64{3, 3}, 4},
+		{"last page", 1, []string{"rom"}, 10, 3, []int{3}, []float64{1}, 4},
+		{"offset past the end", 1, []string{"rom"}, 10, 4, nil, nil, 4},
+	}
+
+	m := newTestMemory(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, total, err := m.Search(tt.userID, tt.terms, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var ids []int
+			var scores []float64
+			for _, r := range results {
+				ids = append(ids, r.Document.FileId)
+				scores = append(scores, r.Score)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || !reflect.DeepEqual(scores, tt.wantScores) || total != tt.wantTotal {
+				t.Errorf("Search() = %v %v, total %d; want %v %v, total %d", ids, scores, total, tt.wantIDs, tt.wantScores, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexCopiesDocument(t *testing.T) {
+	m := NewMemory()
+	doc := &models.SearchDocument{FileId: 1, UserId: 1, Title: "Rome", Tags: []string{"history"}}
+	if err := m.Index(doc); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	doc.Title = "Paris"
+	doc.Tags[0] = "art"
+
+	if results, _, _ := m.Search(1, []string{"hist"}, 10, 0); len(results) != 1 || results[0].Document.Title != "Rome" {
+		t.Errorf("Search() after changing the indexed document = %+v, want the document as indexed", results)
+	}
+}
+
+func TestMemoryDelete(t *testing.T) {
+	m := newTestMemory(t)
+	if err := m.Delete(2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	results, total, _ := m.Search(1, []string{"rom"}, 10, 0)
+	for _, r := range results {
+		if r.Document.FileId == 2 {
+			t.Errorf("Search() returned deleted document 2")
+		}
+	}
+	if total != 3 {
+		t.Errorf("Search() total = %d after delete, want 3", total)
+	}
+}
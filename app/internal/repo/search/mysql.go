@@ -0,0 +1,72 @@
+package search
+
+import (
+	"flag"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	mysqlMinTermLength = flag.Int("searchMySQLMinTermLength", 3, "shortest term the MySQL index is queried with, should match innodb_ft_min_token_size")
+	// The default is INFORMATION_SCHEMA.INNODB_FT_DEFAULT_STOPWORD.
+	mysqlStopwords = flag.String("searchMySQLStopwords",
+		"a,about,an,are,as,at,be,by,com,de,en,for,from,how,i,in,is,it,la,of,on,or,that,the,this,to,was,what,when,where,who,will,with,und,www",
+		"comma separated words the MySQL index does not store, should match innodb_ft_server_stopword_table")
+
+	stopwords     map[string]bool
+	stopwordsOnce sync.Once
+)
+
+// MySQL searches the video_search table through its FULLTEXT indexes.
+type MySQL struct{}
+
+func NewMySQL() *MySQL {
+	return &MySQL{}
+}
+
+func (m *MySQL) Index(doc *models.SearchDocument) error {
+	return mysql.GetConnection().UpsertSearchDocument(doc)
+}
+
+func (m *MySQL) Delete(fileID int) error {
+	return mysql.GetConnection().DeleteSearchDocument(fileID)
+}
+
+// Search requires every term as a word prefix. InnoDB does not index stopwords or words shorter than its
+// minimum token size, so such terms are left out rather than making the query match nothing.
+func (m *MySQL) Search(userID int, terms []string, limit, offset int) ([]*Result, int, error) {
+	var query []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) >= *mysqlMinTermLength && !isStopword(term) {
+			query = append(query, "+"+term+"*")
+		}
+	}
+	if len(query) == 0 {
+		return nil, 0, nil
+	}
+
+	docs, scores, total, err := mysql.GetConnection().SearchDocuments(userID, strings.Join(query, " "), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := make([]*Result, len(docs))
+	for i, doc := range docs {
+		results[i] = &Result{Document: doc, Score: scores[i]}
+	}
+	return results, total, nil
+}
+
+func isStopword(term string) bool {
+	stopwordsOnce.Do(func() {
+		stopwords = make(map[string]bool)
+		for _, word := range strings.Split(*mysqlStopwords, ",") {
+			if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+				stopwords[word] = true
+			}
+		}
+	})
+	return stopwords[term]
+}
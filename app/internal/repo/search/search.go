@@ -0,0 +1,77 @@
+package search
+
+import (
+	"flag"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"log"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const maxQueryTerms = 10
+
+var (
+	driver = flag.String("searchDriver", "mysql", "full-text search index: mysql or memory")
+	index  Index
+	once   sync.Once
+)
+
+type Result struct {
+	Document *models.SearchDocument
+	Score    float64
+}
+
+// Index keeps the searchable documents of all users. Search only returns documents of the given user that
+// contain every term, as a word or a word prefix, best matches first, along with the total number of matches.
+type Index interface {
+	Index(doc *models.SearchDocument) error
+	Delete(fileID int) error
+	Search(userID int, terms []string, limit, offset int) ([]*Result, int, error)
+}
+
+func initIndex() {
+	switch *driver {
+	case "mysql":
+		index = NewMySQL()
+	case "memory":
+		index = NewMemory()
+	default:
+		log.Fatalf("unknown search driver: %s", *driver)
+	}
+}
+
+func GetIndex() Index {
+	once.Do(func() {
+		initIndex()
+	})
+
+	return index
+}
+
+// Terms splits a query into distinct lower case words, dropping punctuation and any query syntax.
+func Terms(query string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if len(terms) == maxQueryTerms {
+			break
+		}
+		if !contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func contains(terms []string, word string) bool {
+	for _, term := range terms {
+		if term == word {
+			return true
+		}
+	}
+	return false
+}
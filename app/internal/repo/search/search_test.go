@@ -0,0 +1,53 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"only separators", " ,.-!? ", nil},
+		{"lower cased", "History of ROME", []string{"history", "of", "rome"}},
+		{"duplicates dropped", "rome Rome ROME", []string{"rome"}},
+		{"boolean syntax dropped", `+cats -dogs "big* cats" (x)~`, []string{"cats", "dogs", "big", "x"}},
+		{"digits kept", "episode 12b", []string{"episode", "12b"}},
+		{"non-latin letters", "Привет, мир! café", []string{"привет", "мир", "café"}},
+		{"apostrophe splits", "don't", []string{"don", "t"}},
+		{"at most ten terms", "a b c d e f g h i j k l", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}},
+		{"duplicates do not count towards the limit", "a a b c d e f g h i j", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsStopword(t *testing.T) {
+	tests := []struct {
+		term string
+		want bool
+	}{
+		{"the", true},
+		{"of", true},
+		{"how", true},
+		{"www", true},
+		{"history", false},
+		{"theater", false},
+	}
+
+	for _, tt := range tests {
+		if got := isStopword(tt.term); got != tt.want {
+			t.Errorf("isStopword(%q) = %v, want %v", tt.term, got, tt.want)
+		}
+	}
+}
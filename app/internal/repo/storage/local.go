@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/pkg/lib"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Local struct{}
+
+func NewLocal() *Local {
+	return &Local{}
+}
+
+func (l *Local) Put(key string, r io.Reader) (int64, error) {
+	dir := filepath.Dir(key)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return 0, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err = os.Rename(tmp.Name(), key); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// MoveFile renames the file into place, which only works within one filesystem.
+func (l *Local) MoveFile(path, key string) error {
+	if err := os.MkdirAll(filepath.Dir(key), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(path, key)
+}
+
+func (l *Local) Get(key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(key)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// The bare *os.File lets fasthttp serve open-ended reads with sendfile.
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+func (l *Local) Delete(key string) error {
+	if strings.HasSuffix(key, "/") {
+		return os.RemoveAll(key)
+	}
+	if err := os.Remove(key); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) Stat(key string) (*ObjectInfo, error) {
+	info, err := os.Stat(key)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ObjectInfo{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		ETag:    fmt.Sprintf("\"%x-%x\"", info.ModTime().UnixNano(), info.Size()),
+	}, nil
+}
+
+func (l *Local) PublicURL(key string) string {
+	return lib.GetVideoPublicLink(key)
+}
+
+func (l *Local) KeyForURL(publicURL string) (string, bool) {
+	key := lib.GetVideoLocalLink(publicURL)
+	return key, key != publicURL
+}
@@ -0,0 +1,377 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+var (
+	s3Endpoint  = flag.String("s3Endpoint", "http://127.0.0.1:9000", "S3-compatible endpoint")
+	s3Region    = flag.String("s3Region", "us-east-1", "S3 region")
+	s3Bucket    = flag.String("s3Bucket", "", "S3 bucket")
+	s3AccessKey = flag.String("s3AccessKey", "", "S3 access key")
+	s3SecretKey = flag.String("s3SecretKey", "", "S3 secret key")
+	s3PublicURL = flag.String("s3PublicURL", "", "public base URL of the bucket, defaults to endpoint/bucket")
+	s3PartSize  = flag.Int("s3PartSize", 16*1024*1024, "multipart upload part size in bytes")
+	s3Timeout   = flag.Duration("s3Timeout", time.Minute, "limit for an S3 request; object downloads are limited until the response headers arrive")
+)
+
+// S3 talks to any S3-compatible service (AWS, MinIO) using path-style requests signed with SigV4.
+// Downloads go through streamClient, which has no overall timeout because their bodies are streamed
+// to viewers for as long as playback takes.
+type S3 struct {
+	endpoint     *url.URL
+	client       *http.Client
+	streamClient *http.Client
+}
+
+func NewS3() (*S3, error) {
+	if *s3Bucket == "" {
+		return nil, errors.New("s3Bucket is required")
+	}
+	endpoint, err := url.Parse(*s3Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3Endpoint: %w", err)
+	}
+	if *s3PartSize < 5*1024*1024 {
+		return nil, errors.New("s3PartSize must be at least 5MB")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: *s3Timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = *s3Timeout
+	transport.ResponseHeaderTimeout = *s3Timeout
+
+	return &S3{
+		endpoint:     endpoint,
+		client:       &http.Client{Transport: transport, Timeout: *s3Timeout},
+		streamClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (s *S3) Put(key string, r io.Reader) (int64, error) {
+	buf := make([]byte, *s3PartSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		resp, err := s.do(http.MethodPut, key, nil, bytes.NewReader(buf[:n]), int64(n), nil)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return int64(n), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	uploadID, err := s.createMultipartUpload(key)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := s.uploadParts(key, uploadID, buf, n, r)
+	if err != nil {
+		if resp, abortErr := s.do(http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, 0, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *S3) Get(key string, offset, length int64) (io.ReadCloser, error) {
+	headers := map[string]string{}
+	switch {
+	case length == 0:
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	case length > 0:
+		headers["Range"] = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	case offset > 0:
+		headers["Range"] = fmt.Sprintf("bytes=%d-", offset)
+	}
+
+	resp, err := s.send(s.streamClient, http.MethodGet, key, nil, nil, 0, headers)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(key string) error {
+	if !strings.HasSuffix(key, "/") {
+		resp, err := s.do(http.MethodDelete, key, nil, nil, 0, nil)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil
+	}
+
+	keys, err := s.list(key)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err = s.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3) Stat(key string) (*ObjectInfo, error) {
+	resp, err := s.do(http.MethodHead, key, nil, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &ObjectInfo{
+		Size:    resp.ContentLength,
+		ModTime: modTime,
+		ETag:    resp.Header.Get("ETag"),
+	}, nil
+}
+
+func (s *S3) PublicURL(key string) string {
+	return s.publicBase() + "/" + uriEncode(strings.TrimPrefix(key, "/"), false)
+}
+
+func (s *S3) KeyForURL(publicURL string) (string, bool) {
+	escaped, ok := strings.CutPrefix(publicURL, s.publicBase()+"/")
+	if !ok {
+		return "", false
+	}
+	key, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+func (s *S3) publicBase() string {
+	base := *s3PublicURL
+	if base == "" {
+		base = s.endpoint.Scheme + "://" + s.endpoint.Host + "/" + *s3Bucket
+	}
+	return strings.TrimRight(base, "/")
+}
+
+func (s *S3) createMultipartUpload(key string) (string, error) {
+	resp, err := s.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode multipart upload: %w", err)
+	}
+	return result.UploadID, nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3) uploadParts(key, uploadID string, buf []byte, n int, r io.Reader) (int64, error) {
+	var parts []completedPart
+	var size int64
+
+	for partNumber := 1; n > 0; partNumber++ {
+		query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+		resp, err := s.do(http.MethodPut, key, query, bytes.NewReader(buf[:n]), int64(n), nil)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+		size += int64(n)
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.do(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), int64(len(body)), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return size, nil
+}
+
+func (s *S3) list(prefix string) ([]string, error) {
+	var keys []string
+	query := url.Values{"list-type": {"2"}, "prefix": {strings.TrimPrefix(prefix, "/")}}
+
+	for {
+		resp, err := s.do(http.MethodGet, "", query, nil, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object list: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated {
+			return keys, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func (s *S3) do(method, key string, query url.Values, body io.Reader, contentLength int64, headers map[string]string) (*http.Response, error) {
+	return s.send(s.client, method, key, query, body, contentLength, headers)
+}
+
+func (s *S3) send(client *http.Client, method, key string, query url.Values, body io.Reader, contentLength int64, headers map[string]string) (*http.Response, error) {
+	canonicalPath := "/" + *s3Bucket
+	if key != "" {
+		canonicalPath += "/" + uriEncode(strings.TrimPrefix(key, "/"), false)
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	rawURL := s.endpoint.Scheme + "://" + s.endpoint.Host + canonicalPath
+	if canonicalQuery != "" {
+		rawURL += "?" + canonicalQuery
+	}
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = contentLength
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.sign(req, canonicalPath, canonicalQuery)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s failed with status %d: %s", method, key, resp.StatusCode, msg)
+	}
+	return resp, nil
+}
+
+func (s *S3) sign(req *http.Request, canonicalPath, canonicalQuery string) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", unsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + unsignedPayload + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method, canonicalPath, canonicalQuery, canonicalHeaders, signedHeaders, unsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + *s3Region + "/s3/aws4_request"
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashedRequest[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+*s3SecretKey), date)
+	signingKey = hmacSHA256(signingKey, *s3Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		*s3AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
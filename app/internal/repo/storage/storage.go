@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	driver  = flag.String("storageDriver", "local", "storage backend: local or s3")
+	backend Backend
+	once    sync.Once
+
+	ErrNotFound = errors.New("object not found")
+)
+
+type ObjectInfo struct {
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// Backend stores video objects. Keys are the paths recorded in files.filepath.
+type Backend interface {
+	Put(key string, r io.Reader) (int64, error)
+	// Get returns length bytes starting at offset, or the rest of the object when length is negative.
+	Get(key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the object at key, or every object under it when key ends with a slash.
+	Delete(key string) error
+	Stat(key string) (*ObjectInfo, error)
+	PublicURL(key string) string
+	// KeyForURL maps a URL produced by PublicURL (or by the converter) back to its key.
+	KeyForURL(publicURL string) (string, bool)
+}
+
+// FileMover is implemented by backends that can take over a local file without copying it.
+type FileMover interface {
+	// MoveFile moves the file at path to key. It fails rather than copies when that is not possible.
+	MoveFile(path, key string) error
+}
+
+func initBackend() {
+	switch *driver {
+	case "local":
+		backend = NewLocal()
+	case "s3":
+		b, err := NewS3()
+		if err != nil {
+			log.Fatal(err)
+		}
+		backend = b
+	default:
+		log.Fatalf("unknown storage driver: %s", *driver)
+	}
+}
+
+func GetBackend() Backend {
+	once.Do(func() {
+		initBackend()
+	})
+
+	return backend
+}
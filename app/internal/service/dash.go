@@ -0,0 +1,61 @@
+package service
+
+import (
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/pkg/manifest"
+	"net/url"
+)
+
+func GetDASHManifest(id, userID int, clientIP string) (string, error) {
+	formats, err := getOwnedFormats(id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	var duration float64
+	metadata, err := mysql.GetConnection().GetVideoMetadata(id)
+	if err != nil {
+		return "", err
+	}
+	if metadata != nil {
+		duration = float64(metadata.DurationMs) / 1000
+	}
+
+	variants := make([]manifest.Variant, 0, len(formats))
+	for _, format := range formats {
+		variant := formatVariant(format)
+		variant.MimeType = dashMimeType(format)
+		variant.URI = renditionURL(id, format, clientIP)
+
+		if format.InitURL != "" {
+			variant.InitURI = initURL(id, format, clientIP)
+		}
+		var segmentsDuration float64
+		for i, segment := range format.Segments {
+			variant.Segments = append(variant.Segments, manifest.Segment{URI: segmentURL(id, format, i, clientIP), Duration: segment.Duration})
+			segmentsDuration += segment.Duration
+		}
+		duration = max(duration, segmentsDuration)
+		variants = append(variants, variant)
+	}
+	return manifest.DASHManifest(variants, duration)
+}
+
+// dashMimeType reports the container of a representation; fragmented MP4 segments are plain video/mp4 in an MPD.
+func dashMimeType(format models.VideoFormat) string {
+	key := format.URL
+	if len(format.Segments) > 0 {
+		key = format.Segments[0].URL
+	}
+	if u, err := url.Parse(key); err == nil {
+		key = u.Path
+	}
+
+	switch contentType := contentTypeForKey(key); contentType {
+	case "video/iso.segment", "application/octet-stream":
+		return "video/mp4"
+	default:
+		return contentType
+	}
+}
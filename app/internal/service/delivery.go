@@ -0,0 +1,102 @@
+package service
+
+import (
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/Dimoonevs/video-service/app/pkg/signedurl"
+)
+
+const SourceRendition = "source"
+
+func GetVideoLinks(userID int, clientIP string) ([]*models.VideoFormatLinksResp, error) {
+	links, err := mysql.GetConnection().GetVideoLinks(userID)
+	if err != nil {
+		return nil, err
+	}
+	signVideoLinks(links, clientIP)
+	return links, nil
+}
+
+// signVideoLinks replaces the stored rendition and segment URLs with signed delivery URLs when signing is on.
+func signVideoLinks(links []*models.VideoFormatLinksResp, clientIP string) {
+	if !signedurl.Enabled() {
+		return
+	}
+	for _, link := range links {
+		for i, format := range link.Formats {
+			link.Formats[i].URL = renditionURL(link.FileId, format, clientIP)
+			if format.InitURL != "" {
+				link.Formats[i].InitURL = initURL(link.FileId, format, clientIP)
+			}
+			for j := range format.Segments {
+				format.Segments[j].URL = segmentURL(link.FileId, format, j, clientIP)
+			}
+		}
+	}
+}
+
+// GetDeliveryStream resolves a rendition of a file for the signed delivery endpoint, which has no user context.
+func GetDeliveryStream(fileID int, rendition string) (*VideoStream, error) {
+	video, err := mysql.GetConnection().GetVideoByID(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if video.Status == string(models.StatusDeleted) {
+		return nil, mysql.ErrVideoNotFound
+	}
+
+	key := video.FilePath
+	contentType := contentTypeForContainer(video.Container)
+	if rendition != SourceRendition {
+		key, err = renditionKey(fileID, rendition)
+		if err != nil {
+			return nil, err
+		}
+		contentType = contentTypeForKey(key)
+	}
+
+	info, err := storage.GetBackend().Stat(key)
+	if err != nil {
+		return nil, err
+	}
+	return &VideoStream{Key: key, ContentType: contentType, Info: info}, nil
+}
+
+// renditionKey resolves "<rendition>" to the rendition file, "<rendition>/init" to its initialization
+// segment and "<rendition>/<n>" to its n-th segment.
+func renditionKey(fileID int, rendition string) (string, error) {
+	formats, err := mysql.GetConnection().GetVideoFormatsByFileID(fileID)
+	if err != nil {
+		return "", err
+	}
+	for _, format := range formats {
+		publicURL := ""
+		if format.Resolution == rendition {
+			publicURL = format.URL
+		} else if format.InitURL != "" && initRendition(format.Resolution) == rendition {
+			publicURL = format.InitURL
+		} else {
+			for i, segment := range format.Segments {
+				if segmentRendition(format.Resolution, i) == rendition {
+					publicURL = segment.URL
+					break
+				}
+			}
+		}
+		if publicURL == "" {
+			continue
+		}
+		if key, ok := storage.GetBackend().KeyForURL(publicURL); ok {
+			return key, nil
+		}
+	}
+	return "", storage.ErrNotFound
+}
+
+func playbackURL(fileID int, key, clientIP string) string {
+	if signedurl.Enabled() {
+		return signedurl.Sign(fileID, SourceRendition, clientIP)
+	}
+	return storage.GetBackend().PublicURL(key)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength        = 255
+	maxDescriptionLength  = 5000
+	maxTags               = 20
+	maxTagLength          = 50
+	maxCustomFieldsBytes  = 16 * 1024
+	maxCustomFieldsKeys   = 50
+	maxCustomFieldKeySize = 64
+)
+
+var (
+	languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8}){0,3}$`)
+
+	ErrInvalidDetails = errors.New("invalid video details")
+)
+
+// GetVideo returns one of the user's videos with a playback link.
+func GetVideo(id, userID int, clientIP string) (*models.InfoVideosResp, error) {
+	video, err := mysql.GetConnection().GetInfoVideoById(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if video.Status == string(models.StatusDeleted) {
+		video.FilePath = ""
+	} else if video.FilePath != "" {
+		video.FilePath = playbackURL(video.Id, video.FilePath, clientIP)
+	}
+	return video, nil
+}
+
+// UpdateVideoDetails validates and applies a partial update of the user-editable fields and returns the video.
+func UpdateVideoDetails(id, userID int, req *models.VideoDetailsReq, clientIP string) (*models.InfoVideosResp, error) {
+	if err := validateVideoDetails(req); err != nil {
+		return nil, err
+	}
+	if err := mysql.GetConnection().UpdateVideoDetails(id, userID, req); err != nil {
+		return nil, err
+	}
+	if req.Title != nil || req.Description != nil || req.Tags != nil {
+		reindexVideo(id)
+	}
+	return GetVideo(id, userID, clientIP)
+}
+
+func validateVideoDetails(req *models.VideoDetailsReq) error {
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if err := validateText("title", title, maxTitleLength); err != nil {
+			return err
+		}
+		req.Title = &title
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if err := validateText("description", description, maxDescriptionLength); err != nil {
+			return err
+		}
+		req.Description = &description
+	}
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = &tags
+	}
+	if req.Language != nil {
+		language := strings.TrimSpace(*req.Language)
+		if language != "" && !languagePattern.MatchString(language) {
+			return fmt.Errorf("%w: language must be a BCP 47 tag such as en or pt-BR", ErrInvalidDetails)
+		}
+		// The pattern allows longer tags than the language column holds.
+		if len(language) > maxLanguageLength {
+			return fmt.Errorf("%w: language must be at most %d characters", ErrInvalidDetails, maxLanguageLength)
+		}
+		req.Language = &language
+	}
+	if req.CustomFields != nil {
+		if err := validateCustomFields(req.CustomFields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateText(name, text string, maxLength int) error {
+	if !utf8.ValidString(text) || utf8.RuneCountInString(text) > maxLength {
+		return fmt.Errorf("%w: %s must be valid text of at most %d characters", ErrInvalidDetails, name, maxLength)
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return fmt.Errorf("%w: %s contains control characters", ErrInvalidDetails, name)
+		}
+	}
+	return nil
+}
+
+// normalizeTags trims tags and drops duplicates, compared case-insensitively. Tags are stored comma
+// separated, so they may not contain commas.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags", ErrInvalidDetails, maxTags)
+	}
+	normalized := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || strings.Contains(tag, ",") || strings.ContainsRune(tag, '\n') {
+			return nil, fmt.Errorf("%w: tags must be non-empty and may not contain commas", ErrInvalidDetails)
+		}
+		if err := validateText("tag", tag, maxTagLength); err != nil {
+			return nil, err
+		}
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// validateCustomFields accepts null, which clears the fields, or a JSON object of limited size.
+func validateCustomFields(raw json.RawMessage) error {
+	if len(raw) > maxCustomFieldsBytes {
+		return fmt.Errorf("%w: custom_fields must be at most %d bytes", ErrInvalidDetails, maxCustomFieldsBytes)
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return fmt.Errorf("%w: custom_fields must be a JSON object", ErrInvalidDetails)
+	}
+	if len(fields) > maxCustomFieldsKeys {
+		return fmt.Errorf("%w: custom_fields may have at most %d keys", ErrInvalidDetails, maxCustomFieldsKeys)
+	}
+	for key := range fields {
+		if key == "" || len(key) > maxCustomFieldKeySize {
+			return fmt.Errorf("%w: custom_fields keys must be 1 to %d bytes", ErrInvalidDetails, maxCustomFieldKeySize)
+		}
+	}
+	return nil
+}
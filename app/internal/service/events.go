@@ -0,0 +1,72 @@
+package service
+
+import (
+	"flag"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	EventStatus     = "status"
+	EventUploaded   = "uploaded"
+	EventRenditions = "renditions"
+
+	maxEventsPerPoll = 100
+)
+
+var eventCommitLag = flag.Duration("eventCommitLag", 2*time.Second, "age a status change must reach before it is streamed, covering transactions that commit out of id order")
+
+// GetVideoEvents returns the user's events after lastEventID. Finished uploads and conversions get their
+// own event names, and a done event carries the rendition links so the client does not have to fetch them.
+func GetVideoEvents(userID int, lastEventID int64, clientIP string) ([]*models.VideoEvent, error) {
+	entries, err := mysql.GetConnection().GetUserStatusHistorySince(userID, lastEventID, maxEventsPerPoll, *eventCommitLag)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*models.VideoEvent, 0, len(entries))
+	for _, entry := range entries {
+		event := &models.VideoEvent{
+			Id:   entry.Id,
+			Name: EventStatus,
+			Data: &models.VideoEventData{
+				FileId:    entry.FileId,
+				Status:    entry.NewStatus,
+				OldStatus: entry.OldStatus,
+				Reason:    entry.Reason,
+				CreatedAt: entry.CreatedAt,
+			},
+		}
+		switch entry.NewStatus {
+		case models.StatusNoConv:
+			event.Name = EventUploaded
+		case models.StatusDone:
+			event.Name = EventRenditions
+			event.Data.Formats = signedFormats(entry.FileId, clientIP)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// LatestEventID is where a stream without Last-Event-ID starts, so it only sees new events.
+func LatestEventID() (int64, error) {
+	return mysql.GetConnection().GetLatestStatusHistoryID(*eventCommitLag)
+}
+
+func signedFormats(fileID int, clientIP string) []models.VideoFormat {
+	formats, err := mysql.GetConnection().GetVideoFormatsByFileID(fileID)
+	if err != nil {
+		logrus.Errorf("failed to get formats of file %d: %v", fileID, err)
+		return nil
+	}
+	for i, format := range formats {
+		formats[i].URL = renditionURL(fileID, format, clientIP)
+		for j := range format.Segments {
+			format.Segments[j].URL = segmentURL(fileID, format, j, clientIP)
+		}
+	}
+	return formats
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxFolderDepth bounds the recursive subtree and ancestor queries.
+	maxFolderDepth      = 10
+	maxFolderNameLength = 255
+	maxFolderVideos     = 500
+)
+
+var (
+	folderMaxPerUser = flag.Int("folderMaxPerUser", 1000, "folders a user may create")
+
+	ErrInvalidFolder = errors.New("invalid folder")
+	ErrFolderLimit   = errors.New("folder limit reached")
+)
+
+func CreateFolder(userID int, req *models.FolderReq) (*models.Folder, error) {
+	if req.Name == nil {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidFolder)
+	}
+	name, err := folderName(*req.Name)
+	if err != nil {
+		return nil, err
+	}
+	count, err := mysql.GetConnection().CountFolders(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= *folderMaxPerUser {
+		return nil, fmt.Errorf("%w: at most %d folders per user", ErrFolderLimit, *folderMaxPerUser)
+	}
+
+	folder := &models.Folder{UserId: userID, ParentId: req.ParentId.Id, Name: name}
+	if err = mysql.GetConnection().CreateFolder(folder, maxFolderDepth); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+func GetFolders(userID int) ([]*models.Folder, error) {
+	return mysql.GetConnection().GetFolders(userID)
+}
+
+func GetFolder(id, userID int) (*models.Folder, error) {
+	return mysql.GetConnection().GetFolder(id, userID)
+}
+
+// UpdateFolder renames and/or moves a folder.
+func UpdateFolder(id, userID int, req *models.FolderReq) (*models.Folder, error) {
+	if req.Name != nil {
+		name, err := folderName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		req.Name = &name
+	}
+	if req.ParentId.Id != nil && *req.ParentId.Id == id {
+		return nil, mysql.ErrFolderCycle
+	}
+	if err := mysql.GetConnection().UpdateFolder(id, userID, req.Name, req.ParentId, maxFolderDepth); err != nil {
+		return nil, err
+	}
+	return mysql.GetConnection().GetFolder(id, userID)
+}
+
+// DeleteFolder deletes a folder under the given policy, restrict by default. Videos deleted with the folder
+// lose their stored files as with DeleteVideo.
+func DeleteFolder(id, userID int, policy string) (*models.FolderDeleteResp, error) {
+	if policy == "" {
+		policy = models.FolderDeleteRestrict
+	} else if !slices.Contains(models.FolderDeletePolicies, policy) {
+		return nil, fmt.Errorf("%w: policy must be one of %v", ErrInvalidFolder, models.FolderDeletePolicies)
+	}
+
+	resp, deleted, err := mysql.GetConnection().DeleteFolder(id, userID, policy)
+	if err != nil {
+		return nil, err
+	}
+	for _, video := range deleted {
+		reindexVideo(video.Id)
+		if err = deleteParentDir(video.FilePath); err != nil {
+			logrus.Errorf("failed to delete stored files of video %d: %v", video.Id, err)
+		}
+	}
+	return resp, nil
+}
+
+// AddVideosToFolder moves videos into the folder from wherever they are.
+func AddVideosToFolder(folderID, userID int, req *models.FolderVideosReq) error {
+	ids, err := folderVideoIDs(req)
+	if err != nil {
+		return err
+	}
+	return mysql.GetConnection().SetVideosFolder(userID, folderID, ids)
+}
+
+// RemoveVideosFromFolder takes videos out of the folder, leaving them in no folder.
+func RemoveVideosFromFolder(folderID, userID int, req *models.FolderVideosReq) error {
+	ids, err := folderVideoIDs(req)
+	if err != nil {
+		return err
+	}
+	return mysql.GetConnection().RemoveVideosFromFolder(userID, folderID, ids)
+}
+
+func folderVideoIDs(req *models.FolderVideosReq) ([]int, error) {
+	if len(req.VideoIds) == 0 || len(req.VideoIds) > maxFolderVideos {
+		return nil, fmt.Errorf("%w: expected 1 to %d video ids", ErrInvalidFolder, maxFolderVideos)
+	}
+	ids := make([]int, 0, len(req.VideoIds))
+	for _, id := range req.VideoIds {
+		if id <= 0 {
+			return nil, fmt.Errorf("%w: invalid video id %d", ErrInvalidFolder, id)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func folderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsRune(name, '\n') {
+		return "", fmt.Errorf("%w: name must be a non-empty single line", ErrInvalidFolder)
+	}
+	if err := validateText("name", name, maxFolderNameLength); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFolder, err)
+	}
+	return name, nil
+}
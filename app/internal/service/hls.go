@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/pkg/manifest"
+	"github.com/Dimoonevs/video-service/app/pkg/signedurl"
+	"net/url"
+)
+
+// GetHLSMaster lists the segmented renditions. Unsegmented ones are progressive MP4 files, which
+// cannot be HLS media segments, so they are only offered through DASH and the links listing.
+func GetHLSMaster(id, userID int) (string, error) {
+	formats, err := getOwnedFormats(id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	variants := make([]manifest.Variant, 0, len(formats))
+	for _, format := range formats {
+		if len(format.Segments) == 0 {
+			continue
+		}
+		variant := formatVariant(format)
+		variant.URI = url.PathEscape(format.Resolution) + "/index.m3u8"
+		variants = append(variants, variant)
+	}
+	if len(variants) == 0 {
+		return "", ErrRenditionNotFound
+	}
+	return manifest.HLSMaster(variants), nil
+}
+
+func GetHLSMedia(id, userID int, rendition, clientIP string) (string, error) {
+	formats, err := getOwnedFormats(id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, format := range formats {
+		if format.Resolution != rendition || len(format.Segments) == 0 {
+			continue
+		}
+		segments := make([]manifest.Segment, len(format.Segments))
+		for i, segment := range format.Segments {
+			segments[i] = manifest.Segment{URI: segmentURL(id, format, i, clientIP), Duration: segment.Duration}
+		}
+		initURI := ""
+		if format.InitURL != "" {
+			initURI = initURL(id, format, clientIP)
+		}
+		return manifest.HLSMedia(initURI, segments), nil
+	}
+	return "", ErrRenditionNotFound
+}
+
+func getOwnedFormats(id, userID int) ([]models.VideoFormat, error) {
+	video, err := mysql.GetConnection().GetInfoVideoById(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if video.Status != string(models.StatusDone) {
+		return nil, ErrRenditionNotFound
+	}
+
+	formats, err := mysql.GetConnection().GetVideoFormatsByFileID(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(formats) == 0 {
+		return nil, ErrRenditionNotFound
+	}
+	return formats, nil
+}
+
+func formatVariant(format models.VideoFormat) manifest.Variant {
+	width, height := manifest.ParseResolution(format.Resolution)
+	bandwidth := format.Bitrate
+	if bandwidth == 0 {
+		bandwidth = manifest.EstimateBandwidth(height)
+	}
+	return manifest.Variant{
+		Name:      format.Resolution,
+		Bandwidth: bandwidth,
+		Width:     width,
+		Height:    height,
+		Codecs:    format.Codec,
+	}
+}
+
+func renditionURL(id int, format models.VideoFormat, clientIP string) string {
+	if signedurl.Enabled() {
+		return signedurl.Sign(id, format.Resolution, clientIP)
+	}
+	return format.URL
+}
+
+func segmentURL(id int, format models.VideoFormat, index int, clientIP string) string {
+	if signedurl.Enabled() {
+		return signedurl.Sign(id, segmentRendition(format.Resolution, index), clientIP)
+	}
+	return format.Segments[index].URL
+}
+
+func initURL(id int, format models.VideoFormat, clientIP string) string {
+	if signedurl.Enabled() {
+		return signedurl.Sign(id, initRendition(format.Resolution), clientIP)
+	}
+	return format.InitURL
+}
+
+func initRendition(rendition string) string {
+	return rendition + "/init"
+}
+
+func segmentRendition(rendition string, index int) string {
+	return fmt.Sprintf("%s/%d", rendition, index)
+}
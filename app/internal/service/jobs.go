@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/Dimoonevs/video-service/app/pkg/lib"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const maxWorkerIDLength = 64
+
+var (
+	convertOnUpload   = flag.Bool("convertOnUpload", false, "queue a conversion job for every uploaded file; leave off while an external converter polls no_conv files")
+	jobMaxAttempts    = flag.Int("jobMaxAttempts", 5, "conversion attempts before a job fails for good")
+	jobLeaseDuration  = flag.Duration("jobLeaseDuration", time.Minute, "default lease granted to a worker on claim and heartbeat")
+	jobMaxLease       = flag.Duration("jobMaxLease", 10*time.Minute, "longest lease a worker may request")
+	jobRetryBaseDelay = flag.Duration("jobRetryBaseDelay", 30*time.Second, "delay before the first retry of a failed job, doubled on every attempt")
+	jobRetryMaxDelay  = flag.Duration("jobRetryMaxDelay", 30*time.Minute, "upper bound of the retry delay")
+	jobReapInterval   = flag.Duration("jobReapInterval", 15*time.Second, "how often expired leases are returned to the queue")
+
+	fileMaxRetries     = flag.Int("fileMaxRetries", 3, "times a failed file may be sent back to conversion")
+	fileRetryBaseDelay = flag.Duration("fileRetryBaseDelay", time.Minute, "wait after a failure before the first retry of a file, doubled on every retry")
+	fileRetryMaxDelay  = flag.Duration("fileRetryMaxDelay", 6*time.Hour, "upper bound of the wait between retries of a file")
+
+	ErrInvalidJobRequest = errors.New("invalid job request")
+
+	reaperOnce sync.Once
+)
+
+// enqueueConversion is called once an upload is stored; failures leave the file in no_conv.
+func enqueueConversion(filesID int) {
+	if !*convertOnUpload {
+		return
+	}
+	if err := mysql.GetConnection().EnqueueConversionJob(filesID, *jobMaxAttempts); err != nil {
+		logrus.Errorf("failed to enqueue conversion of file %d: %v", filesID, err)
+	}
+}
+
+// RetryFile sends one failed file back to conversion; the job becomes available after the backoff.
+func RetryFile(id, userID int) (*models.RetryResp, error) {
+	return mysql.GetConnection().RetryFile(id, userID, *fileMaxRetries, *jobMaxAttempts, fileRetryDelay)
+}
+
+func RetryFailedStreams(userID int) ([]*models.RetryResp, error) {
+	return mysql.GetConnection().RequeueFailedStreams(userID, *fileMaxRetries, *jobMaxAttempts, fileRetryDelay)
+}
+
+// ClaimJob returns nil when there is nothing to convert.
+func ClaimJob(req *models.JobClaimReq) (*models.ConversionJob, error) {
+	if req.WorkerID == "" || len(req.WorkerID) > maxWorkerIDLength {
+		return nil, fmt.Errorf("%w: worker_id is required and limited to %d characters", ErrInvalidJobRequest, maxWorkerIDLength)
+	}
+	lease, err := leaseSeconds(req.LeaseSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	leaseToken, err := lib.RandomID()
+	if err != nil {
+		return nil, err
+	}
+	job, err := mysql.GetConnection().ClaimConversionJob(req.WorkerID, leaseToken, lease)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	job.SourceURL = storage.GetBackend().PublicURL(job.FilePath)
+	return job, nil
+}
+
+func HeartbeatJob(jobID int64, req *models.JobLeaseReq) (*models.ConversionJob, error) {
+	if req.LeaseToken == "" {
+		return nil, fmt.Errorf("%w: lease_token is required", ErrInvalidJobRequest)
+	}
+	lease, err := leaseSeconds(req.LeaseSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := mysql.GetConnection().ExtendJobLease(jobID, req.LeaseToken, lease)
+	if err != nil {
+		return nil, err
+	}
+	job.SourceURL = storage.GetBackend().PublicURL(job.FilePath)
+	return job, nil
+}
+
+func CompleteJob(jobID int64, req *models.JobLeaseReq) error {
+	if req.LeaseToken == "" {
+		return fmt.Errorf("%w: lease_token is required", ErrInvalidJobRequest)
+	}
+	return mysql.GetConnection().CompleteConversionJob(jobID, req.LeaseToken)
+}
+
+func FailJob(jobID int64, req *models.JobFailReq) error {
+	if req.LeaseToken == "" {
+		return fmt.Errorf("%w: lease_token is required", ErrInvalidJobRequest)
+	}
+	retryable := req.Retryable == nil || *req.Retryable
+	return mysql.GetConnection().FailConversionJob(jobID, req.LeaseToken, req.Error, retryable, retryDelay)
+}
+
+// StartJobReaper periodically returns jobs with expired leases to the queue.
+func StartJobReaper() {
+	reaperOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(*jobReapInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				requeued, err := mysql.GetConnection().RequeueExpiredJobs()
+				if err != nil {
+					logrus.Errorf("failed to reap expired jobs: %v", err)
+					continue
+				}
+				if requeued > 0 {
+					logrus.Infof("returned %d stalled conversion jobs to the queue", requeued)
+				}
+			}
+		}()
+	})
+}
+
+func leaseSeconds(requested int) (int, error) {
+	if requested == 0 {
+		return int(jobLeaseDuration.Seconds()), nil
+	}
+	if requested < 0 || requested > int(jobMaxLease.Seconds()) {
+		return 0, fmt.Errorf("%w: lease_seconds must be between 1 and %d", ErrInvalidJobRequest, int(jobMaxLease.Seconds()))
+	}
+	return requested, nil
+}
+
+// retryDelay doubles the base delay for every attempt already made.
+func retryDelay(attempts int) int {
+	return backoffSeconds(*jobRetryBaseDelay, *jobRetryMaxDelay, attempts)
+}
+
+// fileRetryDelay is the wait between a file's failure and its n-th retry.
+func fileRetryDelay(retry int) int {
+	return backoffSeconds(*fileRetryBaseDelay, *fileRetryMaxDelay, retry)
+}
+
+func backoffSeconds(base, limit time.Duration, n int) int {
+	delay := base
+	for i := 1; i < n && delay < limit; i++ {
+		delay *= 2
+	}
+	return int(min(delay, limit).Seconds())
+}
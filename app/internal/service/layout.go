@@ -0,0 +1,129 @@
+package service
+
+import (
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/pkg/lib"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MigrateStorageLayout moves files saved under the legacy md5(userID_filename) directories
+// into per-file directories, so records that shared a directory no longer share bytes.
+// The legacy layout only ever existed on local disk, so this works on the filesystem directly.
+func MigrateStorageLayout() error {
+	files, err := mysql.GetConnection().GetStoredFiles()
+	if err != nil {
+		return err
+	}
+
+	legacyDirs := make(map[string][]*models.StoredFile)
+	for _, file := range files {
+		if isLegacyPath(file) {
+			dir := filepath.Dir(file.FilePath)
+			legacyDirs[dir] = append(legacyDirs[dir], file)
+		}
+	}
+
+	var moved, copied int
+	for dir, group := range legacyDirs {
+		// The newest record owns whatever is on disk now, older ones get their own copy of the whole
+		// directory, renditions included, since relocate rewrites their rendition links as well.
+		sort.Slice(group, func(i, j int) bool { return group[i].Id > group[j].Id })
+
+		owner := group[0]
+		newPath, err := buildSavePath(filepath.Base(owner.FilePath))
+		if err != nil {
+			return err
+		}
+		newDir := filepath.Dir(newPath)
+		if err = os.Rename(dir, newDir); err != nil {
+			logrus.Errorf("failed to move %s: %v", dir, err)
+			continue
+		}
+		if err = relocate(owner.Id, dir, newPath); err != nil {
+			logrus.Errorf("failed to update file %d: %v", owner.Id, err)
+			if err = os.Rename(newDir, dir); err != nil {
+				logrus.Errorf("failed to move %s back: %v", newDir, err)
+			}
+			continue
+		}
+		moved++
+
+		for _, file := range group[1:] {
+			copyPath, err := buildSavePath(filepath.Base(file.FilePath))
+			if err != nil {
+				return err
+			}
+			if err = copyDir(newDir, filepath.Dir(copyPath)); err != nil {
+				logrus.Errorf("failed to copy file %d: %v", file.Id, err)
+				os.RemoveAll(filepath.Dir(copyPath))
+				continue
+			}
+			if err = relocate(file.Id, dir, copyPath); err != nil {
+				logrus.Errorf("failed to update file %d: %v", file.Id, err)
+				os.RemoveAll(filepath.Dir(copyPath))
+				continue
+			}
+			copied++
+		}
+	}
+
+	logrus.Infof("storage layout migration finished: %d directories moved, %d copied", moved, copied)
+	return nil
+}
+
+func isLegacyPath(file *models.StoredFile) bool {
+	if !strings.HasPrefix(file.FilePath, *pathToSave) {
+		return false
+	}
+	dir := filepath.Base(filepath.Dir(file.FilePath))
+	return dir == hashFilename(file.UserId, filepath.Base(file.FilePath))
+}
+
+func relocate(filesID int, oldDir, newPath string) error {
+	oldLink := lib.GetVideoPublicLink(oldDir + "/")
+	newLink := lib.GetVideoPublicLink(filepath.Dir(newPath) + "/")
+	return mysql.GetConnection().RelocateFile(filesID, newPath, oldLink, newLink)
+}
+
+// copyDir copies the regular files below src into dst, keeping their relative paths.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"slices"
+	"unicode/utf8"
+)
+
+const maxNamePrefixLength = 255
+
+var (
+	videoListDefaultLimit = flag.Int("videoListDefaultLimit", 50, "videos per page when no limit is given")
+	videoListMaxLimit     = flag.Int("videoListMaxLimit", 200, "largest page size a client may request")
+
+	ErrInvalidListRequest = errors.New("invalid list request")
+)
+
+// ListVideos returns one page of the user's videos and the cursor of the next page, if there is one.
+func ListVideos(userID int, req *models.VideoListReq, clientIP string) (*models.VideoListResp, error) {
+	if err := validateListRequest(req); err != nil {
+		return nil, err
+	}
+	after, err := decodeVideoCursor(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.FolderId != 0 {
+		if req.Recursive {
+			req.FolderIds, err = mysql.GetConnection().GetFolderSubtree(req.FolderId, userID)
+		} else {
+			_, err = mysql.GetConnection().GetFolder(req.FolderId, userID)
+			req.FolderIds = []int{req.FolderId}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// One extra row tells whether another page follows.
+	videos, err := mysql.GetConnection().ListVideos(userID, req, after, req.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	total, err := mysql.GetConnection().CountVideos(userID, &req.VideoFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.VideoListResp{Items: videos, Total: total}
+	if len(videos) > req.Limit {
+		resp.Items = videos[:req.Limit]
+		if resp.NextCursor, err = encodeVideoCursor(req, resp.Items[req.Limit-1]); err != nil {
+			return nil, err
+		}
+	}
+	for _, video := range resp.Items {
+		if video.FilePath != "" {
+			video.FilePath = playbackURL(video.Id, video.FilePath, clientIP)
+		}
+	}
+	return resp, nil
+}
+
+func validateListRequest(req *models.VideoListReq) error {
+	if req.Sort == "" {
+		req.Sort = models.SortCreated
+	} else if !slices.Contains(models.VideoSorts, req.Sort) {
+		return fmt.Errorf("%w: sort must be one of %v", ErrInvalidListRequest, models.VideoSorts)
+	}
+	if req.Limit == 0 {
+		req.Limit = *videoListDefaultLimit
+	} else if req.Limit < 0 || req.Limit > *videoListMaxLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListRequest, *videoListMaxLimit)
+	}
+	for _, status := range req.Statuses {
+		if !slices.Contains(models.FileStatuses, models.FileStatus(status)) {
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidListRequest, status)
+		}
+	}
+	if !req.CreatedFrom.IsZero() && !req.CreatedTo.IsZero() && !req.CreatedFrom.Before(req.CreatedTo) {
+		return fmt.Errorf("%w: created_from must be before created_to", ErrInvalidListRequest)
+	}
+	if len(req.NamePrefix) > maxNamePrefixLength || !utf8.ValidString(req.NamePrefix) {
+		return fmt.Errorf("%w: invalid name prefix", ErrInvalidListRequest)
+	}
+	return nil
+}
+
+// encodeVideoCursor builds the opaque cursor pointing after the given video.
+func encodeVideoCursor(req *models.VideoListReq, last *models.InfoVideosResp) (string, error) {
+	cursor := models.VideoCursor{Sort: req.Sort, Desc: req.Desc, Id: last.Id}
+	switch req.Sort {
+	case models.SortCreated:
+		cursor.Value = last.CreatedAt.UnixMilli()
+	case models.SortUpdated:
+		cursor.Value = last.UpdatedAt.UnixMilli()
+	case models.SortName:
+		cursor.Value = last.FileName
+	case models.SortSize:
+		cursor.Value = last.Size
+	case models.SortDuration:
+		var duration int64
+		if last.Metadata != nil {
+			duration = last.Metadata.DurationMs
+		}
+		cursor.Value = duration
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeVideoCursor returns nil for the first page. A cursor only continues the listing it was issued
+// for, so it is rejected when the sort changed.
+func decodeVideoCursor(req *models.VideoListReq) (*models.VideoCursor, error) {
+	if req.Cursor == "" {
+		return nil, nil
+	}
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidListRequest)
+	data, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cursor models.VideoCursor
+	if err = decoder.Decode(&cursor); err != nil || cursor.Id <= 0 {
+		return nil, invalid
+	}
+	if cursor.Sort != req.Sort || cursor.Desc != req.Desc {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidListRequest)
+	}
+
+	switch value := cursor.Value.(type) {
+	case string:
+		if cursor.Sort != models.SortName {
+			return nil, invalid
+		}
+	case json.Number:
+		n, err := value.Int64()
+		if err != nil || cursor.Sort == models.SortName {
+			return nil, invalid
+		}
+		cursor.Value = n
+	default:
+		return nil, invalid
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/pkg/probe"
+	"github.com/sirupsen/logrus"
+	"io"
+)
+
+func storeVideoMetadata(filesID int, src io.ReaderAt, size int64, container *probe.Container) {
+	if container.Format != probe.FormatMP4 && container.Format != probe.FormatMOV {
+		return
+	}
+
+	meta, err := probe.ReadMetadata(src, size)
+	if err != nil {
+		logrus.Errorf("failed to read metadata of file %d: %v", filesID, err)
+		return
+	}
+
+	if err = mysql.GetConnection().SetVideoMetadata(filesID, &models.VideoMetadata{
+		DurationMs: meta.DurationMs,
+		Width:      meta.Width,
+		Height:     meta.Height,
+		FrameRate:  meta.FrameRate,
+		VideoCodec: meta.VideoCodec,
+		AudioCodec: meta.AudioCodec,
+		Bitrate:    meta.Bitrate,
+		Rotation:   meta.Rotation,
+	}); err != nil {
+		logrus.Errorf("failed to store metadata of file %d: %v", filesID, err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"flag"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/broker"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	outboxPollInterval   = flag.Duration("outboxPollInterval", time.Second, "how often the outbox is checked for unpublished events")
+	outboxBatchSize      = flag.Int("outboxBatchSize", 100, "outbox events published per batch")
+	outboxPublishTimeout = flag.Duration("outboxPublishTimeout", 10*time.Second, "timeout of a single broker publish")
+	outboxRetryBaseDelay = flag.Duration("outboxRetryBaseDelay", time.Second, "delay before republishing a failed event, doubled on every attempt")
+	outboxRetryMaxDelay  = flag.Duration("outboxRetryMaxDelay", 5*time.Minute, "upper bound of the republish delay")
+	outboxRetention      = flag.Duration("outboxRetention", 7*24*time.Hour, "how long published events are kept in the outbox")
+	outboxTopicPrefix    = flag.String("outboxTopicPrefix", "video.", "prefix of the broker topic; the event type is appended")
+
+	relayOnce sync.Once
+)
+
+// outboxStore is the part of the database the relay works with.
+type outboxStore interface {
+	ClaimOutboxEvents(limit, leaseSeconds int) ([]*models.OutboxEvent, error)
+	MarkOutboxEventPublished(id int64) error
+	SetOutboxEventError(id int64, lastError string, retryDelaySeconds int) error
+	DelayOutboxEvents(ids []int64, delaySeconds int) error
+}
+
+// StartOutboxRelay publishes outbox events to the configured broker. Events are marked published only
+// after the broker acknowledged them, so a crash in between republishes them: delivery is at least once
+// and consumers deduplicate on the event id.
+func StartOutboxRelay() {
+	if !broker.Enabled() {
+		return
+	}
+	relayOnce.Do(func() {
+		b := broker.GetBroker()
+		go func() {
+			ticker := time.NewTicker(*outboxPollInterval)
+			defer ticker.Stop()
+			lastCleanup := time.Now()
+			for range ticker.C {
+				relayOutbox(mysql.GetConnection(), b)
+				if time.Since(lastCleanup) >= time.Hour {
+					lastCleanup = time.Now()
+					cleanupOutbox()
+				}
+			}
+		}()
+	})
+}
+
+func relayOutbox(store outboxStore, b broker.Broker) {
+	lease := int(outboxPublishTimeout.Seconds())*(*outboxBatchSize) + 1
+	for {
+		events, err := store.ClaimOutboxEvents(*outboxBatchSize, lease)
+		if err != nil {
+			logrus.Errorf("failed to claim outbox events: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+		for i, event := range events {
+			if delay, ok := publishOutboxEvent(store, b, event); !ok {
+				// Events are published in id order, so the rest of the batch waits as long as the failed one.
+				delayOutboxEvents(store, events[i+1:], delay)
+				return
+			}
+		}
+	}
+}
+
+// publishOutboxEvent reports whether the event was published and, if not, in how many seconds it is retried.
+func publishOutboxEvent(store outboxStore, b broker.Broker, event *models.OutboxEvent) (int, bool) {
+	id := strconv.FormatInt(event.Id, 10)
+	msg := &broker.Message{
+		ID:      id,
+		Topic:   *outboxTopicPrefix + event.EventType,
+		Key:     strconv.Itoa(event.AggregateId),
+		Payload: []byte(event.Payload),
+		Headers: map[string]string{
+			"Event-Id":       id,
+			"Event-Type":     event.EventType,
+			"Aggregate-Type": event.AggregateType,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *outboxPublishTimeout)
+	err := b.Publish(ctx, msg)
+	cancel()
+	if err != nil {
+		delay := backoffSeconds(*outboxRetryBaseDelay, *outboxRetryMaxDelay, event.Attempts+1)
+		logrus.Warnf("failed to publish outbox event %d: %v", event.Id, err)
+		if err = store.SetOutboxEventError(event.Id, err.Error(), delay); err != nil {
+			logrus.Errorf("failed to record outbox event %d error: %v", event.Id, err)
+		}
+		return delay, false
+	}
+	if err = store.MarkOutboxEventPublished(event.Id); err != nil {
+		// The event is published again once its lease lapses.
+		logrus.Errorf("failed to mark outbox event %d published: %v", event.Id, err)
+		return 0, false
+	}
+	return 0, true
+}
+
+func delayOutboxEvents(store outboxStore, events []*models.OutboxEvent, delaySeconds int) {
+	if len(events) == 0 {
+		return
+	}
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.Id
+	}
+	if err := store.DelayOutboxEvents(ids, delaySeconds); err != nil {
+		logrus.Errorf("failed to delay outbox events: %v", err)
+	}
+}
+
+func cleanupOutbox() {
+	deleted, err := mysql.GetConnection().DeletePublishedOutboxEvents(int(outboxRetention.Seconds()))
+	if err != nil {
+		logrus.Errorf("failed to clean up outbox: %v", err)
+		return
+	}
+	if deleted > 0 {
+		logrus.Infof("deleted %d published outbox events", deleted)
+	}
+}
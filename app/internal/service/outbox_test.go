@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/broker"
+)
+
+type fakeOutboxEvent struct {
+	models.OutboxEvent
+	nextAttempt int
+	published   bool
+	lastError   string
+}
+
+// fakeOutboxStore keeps the outbox in memory. Time is counted in whole seconds and only moves when the
+// test advances now.
+type fakeOutboxStore struct {
+	t      *testing.T
+	broker *broker.Memory
+	now    int
+	events []*fakeOutboxEvent
+}
+
+func newFakeOutboxStore(t *testing.T, b *broker.Memory, ids ...int64) *fakeOutboxStore {
+	s := &fakeOutboxStore{t: t, broker: b}
+	for _, id := range ids {
+		s.events = append(s.events, &fakeOutboxEvent{OutboxEvent: models.OutboxEvent{
+			Id:            id,
+			AggregateType: "file",
+			AggregateId:   int(id) * 10,
+			EventType:     models.OutboxFileCreated,
+			Payload:       `{"file_id":` + strconv.Itoa(int(id)*10) + `}`,
+		}})
+	}
+	sort.Slice(s.events, func(i, j int) bool { return s.events[i].Id < s.events[j].Id })
+	return s
+}
+
+func (s *fakeOutboxStore) event(id int64) *fakeOutboxEvent {
+	for _, event := range s.events {
+		if event.Id == id {
+			return event
+		}
+	}
+	s.t.Fatalf("no outbox event %d", id)
+	return nil
+}
+
+func (s *fakeOutboxStore) ClaimOutboxEvents(limit, leaseSeconds int) ([]*models.OutboxEvent, error) {
+	var claimed []*models.OutboxEvent
+	for _, event := range s.events {
+		if len(claimed) == limit {
+			break
+		}
+		if event.published || event.nextAttempt > s.now {
+			continue
+		}
+		event.nextAttempt = s.now + leaseSeconds
+		claimed = append(claimed, &models.OutboxEvent{
+			Id:            event.Id,
+			AggregateType: event.AggregateType,
+			AggregateId:   event.AggregateId,
+			EventType:     event.EventType,
+			Payload:       event.Payload,
+			Attempts:      event.Attempts,
+		})
+	}
+	return claimed, nil
+}
+
+func (s *fakeOutboxStore) MarkOutboxEventPublished(id int64) error {
+	if !publishedIDs(s.broker)[strconv.FormatInt(id, 10)] {
+		s.t.Errorf("outbox event %d marked published before the broker received it", id)
+	}
+	event := s.event(id)
+	event.published = true
+	event.Attempts++
+	event.lastError = ""
+	return nil
+}
+
+func (s *fakeOutboxStore) SetOutboxEventError(id int64, lastError string, retryDelaySeconds int) error {
+	event := s.event(id)
+	event.Attempts++
+	event.lastError = lastError
+	event.nextAttempt = s.now + retryDelaySeconds
+	return nil
+}
+
+func (s *fakeOutboxStore) DelayOutboxEvents(ids []int64, delaySeconds int) error {
+	for _, id := range ids {
+		if event := s.event(id); !event.published {
+			event.nextAttempt = s.now + delaySeconds
+		}
+	}
+	return nil
+}
+
+// flakyBroker fails every publish of the event ids in failing and hands the rest to Memory.
+type flakyBroker struct {
+	*broker.Memory
+	failing map[string]bool
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, msg *broker.Message) error {
+	if b.failing[msg.ID] {
+		return errors.New("broker unavailable")
+	}
+	return b.Memory.Publish(ctx, msg)
+}
+
+func publishedIDs(b *broker.Memory) map[string]bool {
+	ids := make(map[string]bool)
+	for _, msg := range b.Messages() {
+		ids[msg.ID] = true
+	}
+	return ids
+}
+
+func messageIDs(b *broker.Memory) []string {
+	var ids []string
+	for _, msg := range b.Messages() {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func setOutboxFlags(t *testing.T, batchSize int, retryBaseDelay time.Duration) {
+	t.Helper()
+	savedBatchSize, savedBaseDelay := *outboxBatchSize, *outboxRetryBaseDelay
+	*outboxBatchSize, *outboxRetryBaseDelay = batchSize, retryBaseDelay
+	t.Cleanup(func() {
+		*outboxBatchSize, *outboxRetryBaseDelay = savedBatchSize, savedBaseDelay
+	})
+}
+
+func TestRelayOutboxPublishesInIDOrder(t *testing.T) {
+	setOutboxFlags(t, 2, time.Second)
+	b := broker.NewMemory()
+	store := newFakeOutboxStore(t, b, 5, 3, 1, 4, 2)
+
+	relayOutbox(store, b)
+
+	if got, want := messageIDs(b), []string{"1", "2", "3", "4", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+	for _, event := range store.events {
+		if !event.published || event.Attempts != 1 {
+			t.Errorf("event %d: published %v after %d attempts, want published after 1", event.Id, event.published, event.Attempts)
+		}
+	}
+
+	msg := b.Messages()[0]
+	want := &broker.Message{
+		ID:      "1",
+		Topic:   *outboxTopicPrefix + models.OutboxFileCreated,
+		Key:     "10",
+		Payload: []byte(`{"file_id":10}`),
+		Headers: map[string]string{"Event-Id": "1", "Event-Type": models.OutboxFileCreated, "Aggregate-Type": "file"},
+	}
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("message = %+v, want %+v", msg, want)
+	}
+}
+
+func TestRelayOutboxRetriesFailedPublish(t *testing.T) {
+	setOutboxFlags(t, 10, 2*time.Second)
+	memory := broker.NewMemory()
+	b := &flakyBroker{Memory: memory, failing: map[string]bool{"2": true}}
+	store := newFakeOutboxStore(t, memory, 1, 2, 3, 4)
+
+	relayOutbox(store, b)
+
+	if got, want := messageIDs(memory), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	failed := store.event(2)
+	if failed.published || failed.Attempts != 1 || failed.lastError == "" || failed.nextAttempt != 2 {
+		t.Errorf("failed event = %+v, want unpublished, 1 attempt, an error and retry at 2s", failed)
+	}
+	for _, id := range []int64{3, 4} {
+		// Later events wait for the failed one so they are not published out of order.
+		if event := store.event(id); event.published || event.Attempts != 0 || event.nextAttempt != 2 {
+			t.Errorf("event %d = %+v, want unpublished, no attempts and delayed to 2s", id, event)
+		}
+	}
+
+	store.now = 1
+	relayOutbox(store, b)
+	if got := len(memory.Messages()); got != 1 {
+		t.Fatalf("published %d messages before the retry delay passed, want 1", got)
+	}
+
+	store.now = 2
+	relayOutbox(store, b)
+	if failed.Attempts != 2 || failed.nextAttempt != 6 {
+		t.Errorf("failed event = %+v, want 2 attempts and the delay doubled to 6s", failed)
+	}
+
+	delete(b.failing, "2")
+	store.now = 6
+	relayOutbox(store, b)
+	if got, want := messageIDs(memory), []string{"1", "2", "3", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v after the retry, want %v", got, want)
+	}
+	for _, event := range store.events {
+		if !event.published || event.lastError != "" {
+			t.Errorf("event %d = %+v, want published without an error", event.Id, event)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/pkg/lib"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const maxPlaylistTitleLength = 255
+
+var (
+	playlistMaxPerUser = flag.Int("playlistMaxPerUser", 200, "playlists a user may create")
+	playlistMaxItems   = flag.Int("playlistMaxItems", 500, "videos a playlist may hold")
+
+	ErrInvalidPlaylist = errors.New("invalid playlist")
+	ErrPlaylistLimit   = errors.New("playlist limit reached")
+)
+
+func CreatePlaylist(userID int, req *models.PlaylistReq) (*models.Playlist, error) {
+	if req.Title == nil {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidPlaylist)
+	}
+	count, err := mysql.GetConnection().CountPlaylists(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= *playlistMaxPerUser {
+		return nil, fmt.Errorf("%w: at most %d playlists per user", ErrPlaylistLimit, *playlistMaxPerUser)
+	}
+
+	playlist := &models.Playlist{UserId: userID, Visibility: models.PlaylistPrivate, Items: []*models.PlaylistItem{}}
+	if err = applyPlaylistReq(playlist, req); err != nil {
+		return nil, err
+	}
+	if playlist.ShareToken, err = lib.RandomID(); err != nil {
+		return nil, err
+	}
+	if err = mysql.GetConnection().CreatePlaylist(playlist); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+func GetPlaylists(userID int) ([]*models.Playlist, error) {
+	return mysql.GetConnection().GetPlaylists(userID)
+}
+
+func GetPlaylist(id, userID int) (*models.Playlist, error) {
+	return mysql.GetConnection().GetPlaylist(id, userID)
+}
+
+func UpdatePlaylist(id, userID int, req *models.PlaylistReq) (*models.Playlist, error) {
+	playlist, err := mysql.GetConnection().GetPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = applyPlaylistReq(playlist, req); err != nil {
+		return nil, err
+	}
+	if req.RotateShareToken {
+		if playlist.ShareToken, err = lib.RandomID(); err != nil {
+			return nil, err
+		}
+	}
+	if err = mysql.GetConnection().UpdatePlaylist(playlist); err != nil {
+		return nil, err
+	}
+	return mysql.GetConnection().GetPlaylist(id, userID)
+}
+
+func DeletePlaylist(id, userID int) error {
+	return mysql.GetConnection().DeletePlaylist(id, userID)
+}
+
+func AddPlaylistItems(id, userID int, req *models.PlaylistItemsReq) (*models.Playlist, error) {
+	if len(req.VideoIds) == 0 || len(req.VideoIds) > *playlistMaxItems {
+		return nil, fmt.Errorf("%w: expected 1 to %d video ids", ErrInvalidPlaylist, *playlistMaxItems)
+	}
+	for i, videoID := range req.VideoIds {
+		if videoID <= 0 || slices.Contains(req.VideoIds[:i], videoID) {
+			return nil, fmt.Errorf("%w: video ids must be distinct and positive", ErrInvalidPlaylist)
+		}
+	}
+	if req.Position != nil && *req.Position < 0 {
+		return nil, fmt.Errorf("%w: position must not be negative", ErrInvalidPlaylist)
+	}
+	if err := mysql.GetConnection().AddPlaylistItems(id, userID, req.VideoIds, req.Position, *playlistMaxItems); err != nil {
+		return nil, err
+	}
+	return mysql.GetConnection().GetPlaylist(id, userID)
+}
+
+func RemovePlaylistItem(id, userID int, itemID int64) (*models.Playlist, error) {
+	if err := mysql.GetConnection().RemovePlaylistItem(id, userID, itemID); err != nil {
+		return nil, err
+	}
+	return mysql.GetConnection().GetPlaylist(id, userID)
+}
+
+func ReorderPlaylistItems(id, userID int, req *models.PlaylistOrderReq) (*models.Playlist, error) {
+	for i, itemID := range req.ItemIds {
+		if slices.Contains(req.ItemIds[:i], itemID) {
+			return nil, mysql.ErrPlaylistOrder
+		}
+	}
+	if err := mysql.GetConnection().ReorderPlaylistItems(id, userID, req.ItemIds); err != nil {
+		return nil, err
+	}
+	return mysql.GetConnection().GetPlaylist(id, userID)
+}
+
+// GetPlaylistFeed returns the owner's playlist for playback.
+func GetPlaylistFeed(id, userID int, clientIP string) (*models.PlaylistFeed, error) {
+	playlist, err := mysql.GetConnection().GetPlaylist(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return playlistFeed(playlist, clientIP)
+}
+
+// GetSharedPlaylistFeed serves playlists to viewers without an account: unlisted and public playlists by
+// share token, public ones also by id.
+func GetSharedPlaylistFeed(key, clientIP string) (*models.PlaylistFeed, error) {
+	id, _ := strconv.Atoi(key)
+	playlist, err := mysql.GetConnection().GetSharedPlaylist(key, id)
+	if err != nil {
+		return nil, err
+	}
+	return playlistFeed(playlist, clientIP)
+}
+
+// playlistFeed lists the playlist's videos that were not deleted, with the links GetVideoLinks gives
+// for them; links are null while a video is not converted yet.
+func playlistFeed(playlist *models.Playlist, clientIP string) (*models.PlaylistFeed, error) {
+	var fileIDs []int
+	for _, item := range playlist.Items {
+		if item.Status != string(models.StatusDeleted) {
+			fileIDs = append(fileIDs, item.FileId)
+		}
+	}
+	links, err := mysql.GetConnection().GetVideoLinksByFileIDs(fileIDs)
+	if err != nil {
+		return nil, err
+	}
+	signVideoLinks(links, clientIP)
+	linksByFile := make(map[int]*models.VideoFormatLinksResp, len(links))
+	for _, link := range links {
+		linksByFile[link.FileId] = link
+	}
+
+	feed := &models.PlaylistFeed{
+		Id:          playlist.Id,
+		Title:       playlist.Title,
+		Description: playlist.Description,
+		Items:       []*models.PlaylistFeedItem{},
+	}
+	for _, item := range playlist.Items {
+		if item.Status == string(models.StatusDeleted) {
+			continue
+		}
+		title := item.Title
+		if title == "" {
+			title = item.FileName
+		}
+		feed.Items = append(feed.Items, &models.PlaylistFeedItem{
+			Position: len(feed.Items),
+			FileId:   item.FileId,
+			Title:    title,
+			Links:    linksByFile[item.FileId],
+		})
+	}
+	return feed, nil
+}
+
+func applyPlaylistReq(playlist *models.Playlist, req *models.PlaylistReq) error {
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return fmt.Errorf("%w: title must not be empty", ErrInvalidPlaylist)
+		}
+		if err := validateText("title", title, maxPlaylistTitleLength); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPlaylist, err)
+		}
+		playlist.Title = title
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if err := validateText("description", description, maxDescriptionLength); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPlaylist, err)
+		}
+		playlist.Description = description
+	}
+	if req.Visibility != nil {
+		if !slices.Contains(models.PlaylistVisibilities, *req.Visibility) {
+			return fmt.Errorf("%w: visibility must be one of %v", ErrInvalidPlaylist, models.PlaylistVisibilities)
+		}
+		playlist.Visibility = *req.Visibility
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/Dimoonevs/video-service/app/pkg/manifest"
+	"net/url"
+	"strings"
+)
+
+const (
+	maxRenditions         = 32
+	maxSegments           = 20000
+	maxCodecLength        = 64
+	maxRenditionBitrate   = 1_000_000_000
+	maxSegmentDurationSec = 600
+)
+
+var ErrInvalidRendition = errors.New("invalid rendition")
+
+// RegisterRenditions validates what the converter produced for a file and stores it as the file's formats.
+func RegisterRenditions(fileID int, req *models.RegisterRenditionsReq) error {
+	if len(req.Renditions) == 0 || len(req.Renditions) > maxRenditions {
+		return fmt.Errorf("%w: expected 1 to %d renditions", ErrInvalidRendition, maxRenditions)
+	}
+
+	seen := make(map[string]bool, len(req.Renditions))
+	formats := make([]models.VideoFormat, 0, len(req.Renditions))
+	for _, rendition := range req.Renditions {
+		format, err := renditionFormat(rendition)
+		if err != nil {
+			return err
+		}
+		if seen[format.Resolution] {
+			return fmt.Errorf("%w: duplicate size %q", ErrInvalidRendition, format.Resolution)
+		}
+		seen[format.Resolution] = true
+		formats = append(formats, format)
+	}
+
+	return mysql.GetConnection().ReplaceVideoFormats(fileID, formats)
+}
+
+func renditionFormat(rendition models.RenditionReq) (models.VideoFormat, error) {
+	format := models.VideoFormat{
+		Resolution: strings.TrimSpace(rendition.Resolution),
+		Bitrate:    rendition.Bitrate,
+		Codec:      strings.TrimSpace(rendition.Codec),
+	}
+
+	if _, height := manifest.ParseResolution(format.Resolution); height <= 0 {
+		return format, fmt.Errorf("%w: unsupported size %q", ErrInvalidRendition, rendition.Resolution)
+	}
+	if format.Bitrate < 0 || format.Bitrate > maxRenditionBitrate {
+		return format, fmt.Errorf("%w: bitrate out of range for %s", ErrInvalidRendition, format.Resolution)
+	}
+	// The codec string ends up quoted inside HLS and DASH manifests.
+	if len(format.Codec) > maxCodecLength || strings.ContainsAny(format.Codec, `"<>&`) {
+		return format, fmt.Errorf("%w: invalid codec for %s", ErrInvalidRendition, format.Resolution)
+	}
+
+	var err error
+	if format.URL, err = renditionLocation(rendition.URL, rendition.Path); err != nil {
+		return format, fmt.Errorf("%s: %w", format.Resolution, err)
+	}
+
+	if len(rendition.Segments) > maxSegments {
+		return format, fmt.Errorf("%w: too many segments for %s", ErrInvalidRendition, format.Resolution)
+	}
+	for i, segment := range rendition.Segments {
+		if segment.Duration <= 0 || segment.Duration > maxSegmentDurationSec {
+			return format, fmt.Errorf("%w: invalid duration of segment %d for %s", ErrInvalidRendition, i, format.Resolution)
+		}
+		location, err := renditionLocation(segment.URL, segment.Path)
+		if err != nil {
+			return format, fmt.Errorf("%s segment %d: %w", format.Resolution, i, err)
+		}
+		format.Segments = append(format.Segments, models.VideoSegment{URL: location, Duration: segment.Duration})
+	}
+
+	// fMP4 segments cannot be decoded without their initialization segment; MPEG-TS segments carry
+	// everything themselves.
+	switch {
+	case rendition.Init != nil && len(format.Segments) == 0:
+		return format, fmt.Errorf("%w: init without segments for %s", ErrInvalidRendition, format.Resolution)
+	case rendition.Init != nil:
+		if format.InitURL, err = renditionLocation(rendition.Init.URL, rendition.Init.Path); err != nil {
+			return format, fmt.Errorf("%s init: %w", format.Resolution, err)
+		}
+	case len(format.Segments) > 0 && !allTransportStream(format.Segments):
+		return format, fmt.Errorf("%w: init is required for the fMP4 segments of %s", ErrInvalidRendition, format.Resolution)
+	}
+	return format, nil
+}
+
+func allTransportStream(segments []models.VideoSegment) bool {
+	for _, segment := range segments {
+		if contentTypeForKey(segmentPath(segment.URL)) != "video/mp2t" {
+			return false
+		}
+	}
+	return true
+}
+
+func segmentPath(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Path
+	}
+	return rawURL
+}
+
+// renditionLocation accepts either a public URL or a storage key and returns the public URL,
+// which must map back to a key so delivery can serve it.
+func renditionLocation(rawURL, key string) (string, error) {
+	backend := storage.GetBackend()
+	switch {
+	case rawURL != "" && key != "":
+		return "", fmt.Errorf("%w: url and path are mutually exclusive", ErrInvalidRendition)
+	case key != "":
+		for _, part := range strings.Split(key, "/") {
+			if part == ".." {
+				return "", fmt.Errorf("%w: path must not contain ..", ErrInvalidRendition)
+			}
+		}
+		return backend.PublicURL(key), nil
+	case rawURL != "":
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("%w: invalid url %q", ErrInvalidRendition, rawURL)
+		}
+		if _, ok := backend.KeyForURL(rawURL); !ok {
+			return "", fmt.Errorf("%w: url %q is outside of the storage", ErrInvalidRendition, rawURL)
+		}
+		return rawURL, nil
+	default:
+		return "", fmt.Errorf("%w: url or path is required", ErrInvalidRendition)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/search"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxSearchQueryLength = 256
+	maxSearchOffset      = 1000
+	maxLanguageLength    = 16
+)
+
+var (
+	searchDefaultLimit = flag.Int("searchDefaultLimit", 20, "search results per page when no limit is given")
+	searchMaxLimit     = flag.Int("searchMaxLimit", 50, "largest search page a client may request")
+	maxTranscriptBytes = flag.Int("maxTranscriptBytes", 2*1024*1024, "largest transcript accepted from the AI pipeline")
+
+	ErrInvalidSearch     = errors.New("invalid search")
+	ErrInvalidTranscript = errors.New("invalid transcript")
+)
+
+// SearchVideos returns the caller's videos matching every word of the query, best first.
+func SearchVideos(userID int, query string, limit, offset int) (*models.SearchResp, error) {
+	if len(query) > maxSearchQueryLength || !utf8.ValidString(query) {
+		return nil, fmt.Errorf("%w: query must be valid text of at most %d bytes", ErrInvalidSearch, maxSearchQueryLength)
+	}
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: query has no words", ErrInvalidSearch)
+	}
+	if limit == 0 {
+		limit = *searchDefaultLimit
+	} else if limit < 0 || limit > *searchMaxLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSearch, *searchMaxLimit)
+	}
+	if offset < 0 || offset > maxSearchOffset {
+		return nil, fmt.Errorf("%w: offset must be between 0 and %d", ErrInvalidSearch, maxSearchOffset)
+	}
+
+	results, total, err := search.GetIndex().Search(userID, terms, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.SearchResp{Items: make([]*models.SearchHit, 0, len(results)), Total: total}
+	for _, result := range results {
+		resp.Items = append(resp.Items, &models.SearchHit{
+			Id:         result.Document.FileId,
+			FileName:   result.Document.FileName,
+			Title:      result.Document.Title,
+			Score:      result.Score,
+			Highlights: search.Highlight(result.Document, terms),
+		})
+	}
+	return resp, nil
+}
+
+// SetTranscript stores the transcript the AI pipeline produced for a file and makes it searchable.
+func SetTranscript(fileID int, req *models.TranscriptReq) error {
+	req.Language = strings.TrimSpace(req.Language)
+	if len(req.Language) > maxLanguageLength {
+		return fmt.Errorf("%w: language must be at most %d characters", ErrInvalidTranscript, maxLanguageLength)
+	}
+	if len(req.Text) > *maxTranscriptBytes || !utf8.ValidString(req.Text) {
+		return fmt.Errorf("%w: text must be valid UTF-8 of at most %d bytes", ErrInvalidTranscript, *maxTranscriptBytes)
+	}
+	if err := mysql.GetConnection().SetTranscript(fileID, req.Language, req.Text); err != nil {
+		return err
+	}
+	reindexVideo(fileID)
+	return nil
+}
+
+// reindexVideo brings the search index in line with the file after one of its searchable fields changed.
+// Failures are only logged; the index is rebuilt from the source tables on the next change.
+func reindexVideo(fileID int) {
+	doc, status, err := mysql.GetConnection().GetSearchDocument(fileID)
+	if err != nil {
+		logrus.Errorf("failed to load search document of file %d: %v", fileID, err)
+		return
+	}
+	if status == models.StatusDeleted {
+		err = search.GetIndex().Delete(fileID)
+	} else {
+		err = search.GetIndex().Index(doc)
+	}
+	if err != nil {
+		logrus.Errorf("failed to index file %d: %v", fileID, err)
+	}
+}
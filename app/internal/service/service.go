@@ -2,34 +2,39 @@ package service
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/Dimoonevs/video-service/app/internal/models"
 	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
 	"github.com/Dimoonevs/video-service/app/pkg/lib"
+	"github.com/Dimoonevs/video-service/app/pkg/probe"
 	"github.com/sirupsen/logrus"
 	"io"
 	"mime/multipart"
-	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 )
 
 var (
-	pathToSave = flag.String("pathToSave", "", "path to save the file")
+	pathToSave        = flag.String("pathToSave", "", "path to save the file")
+	maxUploadSize     = flag.Int64("maxUploadSize", 20*1024*1024*1024, "max size of a single uploaded file in bytes")
+	allowedContainers = flag.String("allowedContainers", "mp4,mov,mkv,webm,avi", "comma separated list of accepted source containers")
 )
 
-func SaveFile(files []*multipart.FileHeader, isStreams bool, id int) {
-	var skippedFiles []string
-	if err := saveFileDiskAndDB(files, &skippedFiles, isStreams, id); err != nil {
-		logrus.Errorf("unable to save file: %v", err)
-	}
-	if len(skippedFiles) > 0 {
-		logrus.Errorf("skipped files: %v", skippedFiles)
+func SaveFile(files []*multipart.FileHeader, isStreams bool, id int) []*models.UploadFileResult {
+	results := saveFileDiskAndDB(files, isStreams, id)
+	for _, result := range results {
+		if result.Status != models.UploadAccepted {
+			logrus.Errorf("file %s not saved: %s (%s)", result.FileName, result.Status, result.Reason)
+		}
 	}
+	return results
 }
 
 func DeleteVideo(id int, userID int) error {
@@ -37,111 +42,174 @@ func DeleteVideo(id int, userID int) error {
 	if err != nil {
 		return err
 	}
-	if videoInfo.Status == "deleted" {
-		return errors.New("video is delete")
-	}
-	if err = deleteParentDir(videoInfo.FilePath); err != nil {
-		return err
-	}
+	// The status moves first so a refused transition leaves the stored files untouched.
 	videoInfo.FileName = fmt.Sprintf("_%s_%d", "deleted", id)
-	err = mysql.GetConnection().DeleteVideo(videoInfo.FileName, id, userID)
-	if err != nil {
+	if err = mysql.GetConnection().DeleteVideo(videoInfo.FileName, id, userID); err != nil {
 		return err
 	}
+	reindexVideo(id)
+	if err = deleteParentDir(videoInfo.FilePath); err != nil {
+		logrus.Errorf("failed to delete stored files of video %d: %v", id, err)
+	}
 	return nil
 }
 
-func saveBytesToDisk(data []byte, savePath string) error {
-	dir := filepath.Dir(savePath)
-	err := os.MkdirAll(dir, os.ModePerm)
+// setStatus is used by background steps that have no caller to report a refused transition to.
+func setStatus(filesID int, status models.FileStatus, reason string) {
+	change := models.StatusChange{Actor: models.ActorSystem, Reason: reason}
+	if err := mysql.GetConnection().SetStatusByFilesID(filesID, status, change); err != nil {
+		logrus.Errorf("failed to set status %s on file %d: %v", status, filesID, err)
+	}
+}
+
+func saveToStorage(src io.Reader, key string) (int64, string, error) {
+	hasher := sha256.New()
+	limited := &io.LimitedReader{R: src, N: *maxUploadSize + 1}
+	size, err := storage.GetBackend().Put(key, io.TeeReader(limited, hasher))
 	if err != nil {
-		logrus.Errorf("mkdir error: %v", err)
-		return err
+		logrus.Errorf("write error: %v", err)
+		return 0, "", err
+	}
+	if size > *maxUploadSize {
+		if err = storage.GetBackend().Delete(key); err != nil {
+			logrus.Errorf("failed to delete oversized file %s: %v", key, err)
+		}
+		return 0, "", ErrUploadTooLarge
 	}
 
-	dst, err := os.Create(savePath)
+	return size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func sniffUpload(file *multipart.FileHeader) (*probe.Container, error) {
+	src, err := file.Open()
 	if err != nil {
-		logrus.Errorf("create file error: %v", err)
-		return err
+		return nil, err
 	}
-	defer dst.Close()
+	defer src.Close()
+
+	return detectContainer(src, file.Size)
+}
 
-	_, err = dst.Write(data)
+func detectContainer(src io.ReaderAt, size int64) (*probe.Container, error) {
+	container, err := probe.Detect(src, size)
 	if err != nil {
-		logrus.Errorf("write error: %v", err)
-		return err
+		return nil, ErrUnsupportedFormat
 	}
+	if !isAllowedContainer(container.Format) {
+		return nil, ErrUnsupportedFormat
+	}
+	return container, nil
+}
 
-	return nil
+func isAllowedContainer(format string) bool {
+	for _, allowed := range strings.Split(*allowedContainers, ",") {
+		if strings.EqualFold(strings.TrimSpace(allowed), format) {
+			return true
+		}
+	}
+	return false
 }
 
+// deleteParentDir deletes the per-file directory holding filePath. Anything not shaped like
+// pathToSave/<id>/ is refused, so a bad path can never take out the storage root or the working directory.
 func deleteParentDir(filePath string) error {
-	parentDir := filepath.Dir(filePath)
-
-	err := os.RemoveAll(parentDir)
-	if err != nil {
-		return err
+	dir := path.Dir(filePath) + "/"
+	id, ok := strings.CutPrefix(dir, *pathToSave)
+	if filePath == "" || !ok || strings.Count(id, "/") != 1 || id == "/" || id == "./" || id == "../" {
+		return fmt.Errorf("refusing to delete %q: not a per-file directory", dir)
 	}
-	return nil
+	return storage.GetBackend().Delete(dir)
 }
 
-func saveFileDiskAndDB(files []*multipart.FileHeader, skippedFiles *[]string, isStreams bool, id int) error {
+func saveFileDiskAndDB(files []*multipart.FileHeader, isStreams bool, id int) []*models.UploadFileResult {
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(files))
+	results := make([]*models.UploadFileResult, len(files))
 
-	for _, file := range files {
-		savePath := *pathToSave + hashFilename(id, file.Filename) + "/" + file.Filename
+	for i, file := range files {
+		result := &models.UploadFileResult{FileName: file.Filename}
+		results[i] = result
 
-		if !lib.IsMP4(file.Filename) {
-			*skippedFiles = append(*skippedFiles, file.Filename)
+		if file.Size > *maxUploadSize {
+			result.Status = models.UploadSkipped
+			result.Reason = ErrUploadTooLarge.Error()
 			continue
 		}
 
-		src, err := file.Open()
+		container, err := sniffUpload(file)
 		if err != nil {
-			logrus.Errorf("failed to open file %s: %v", file.Filename, err)
+			result.Status = models.UploadSkipped
+			result.Reason = "unsupported type"
 			continue
 		}
-		defer src.Close()
 
-		fileBytes, err := io.ReadAll(src)
+		savePath, err := buildSavePath(file.Filename)
 		if err != nil {
-			logrus.Errorf("failed to read file %s: %v", file.Filename, err)
+			result.Status = models.UploadFailed
+			result.Reason = "failed to allocate storage"
 			continue
 		}
 
 		filesId, err := mysql.GetConnection().SetFilesData(file.Filename, savePath, isStreams, id)
 		if err != nil {
 			logrus.Errorf("SetFilesData failed for %s: %v", file.Filename, err)
+			if errors.Is(err, mysql.ErrDuplicateFile) {
+				result.Status = models.UploadDuplicate
+				result.Reason = "file with the same name already exists"
+			} else {
+				result.Status = models.UploadFailed
+				result.Reason = "failed to register file"
+			}
 			continue
 		}
+		result.Id = filesId
+		reindexVideo(filesId)
+		if err = mysql.GetConnection().SetFileContainer(filesId, container.Format, container.Brand); err != nil {
+			logrus.Errorf("failed to store container of file %s: %v", file.Filename, err)
+		}
 
 		wg.Add(1)
-		go func(path string, data []byte, filesId int, filename string) {
+		go func(path string, file *multipart.FileHeader, result *models.UploadFileResult, container *probe.Container) {
 			defer wg.Done()
-			if err := saveBytesToDisk(data, path); err != nil {
-				mysql.GetConnection().SetStatusByFilesID(filesId, models.StatusLoadError)
-				errChan <- fmt.Errorf("error while saving file %s: %w", filename, err)
+			src, err := file.Open()
+			if err != nil {
+				logrus.Errorf("failed to open file %s: %v", file.Filename, err)
+				result.Status = models.UploadFailed
+				result.Reason = "failed to read uploaded file"
+				setStatus(result.Id, models.StatusLoadError, result.Reason)
+				return
+			}
+			defer src.Close()
+
+			size, checksum, err := saveToStorage(src, path)
+			if err != nil {
+				logrus.Errorf("error while saving file %s: %v", file.Filename, err)
+				result.Status = models.UploadFailed
+				result.Reason = "failed to save file"
+				setStatus(result.Id, models.StatusLoadError, result.Reason)
 				return
 			}
 
-			mysql.GetConnection().SetStatusByFilesID(filesId, models.StatusNoConv)
-			logrus.Infof("file %s saved successfully", filename)
-		}(savePath, fileBytes, filesId, file.Filename)
+			if err = mysql.GetConnection().SetFileChecksum(result.Id, size, checksum); err != nil {
+				logrus.Errorf("failed to store checksum of file %s: %v", file.Filename, err)
+			}
+			storeVideoMetadata(result.Id, src, file.Size, container)
+			setStatus(result.Id, models.StatusNoConv, "upload stored")
+			enqueueConversion(result.Id)
+			result.Status = models.UploadAccepted
+			logrus.Infof("file %s saved successfully", file.Filename)
+		}(savePath, file, result, container)
 	}
 
 	wg.Wait()
-	close(errChan)
-
-	var errStrings []string
-	for err := range errChan {
-		errStrings = append(errStrings, err.Error())
-	}
+	return results
+}
 
-	if len(errStrings) > 0 {
-		return errors.New(strings.Join(errStrings, "; "))
+func buildSavePath(filename string) (string, error) {
+	key, err := lib.RandomID()
+	if err != nil {
+		return "", err
 	}
-	return nil
+	return *pathToSave + key + "/" + filepath.Base(filename), nil
 }
 
 func hashFilename(userID int, filename string) string {
@@ -149,3 +217,10 @@ func hashFilename(userID int, filename string) string {
 	hasher.Write([]byte(fmt.Sprintf("%d_%s", userID, filename)))
 	return hex.EncodeToString(hasher.Sum(nil))
 }
+
+func GetStatusHistory(id, userID int) ([]*models.StatusHistoryEntry, error) {
+	if _, err := mysql.GetConnection().GetInfoVideoById(id, userID); err != nil {
+		return nil, err
+	}
+	return mysql.GetConnection().GetStatusHistory(id)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/Dimoonevs/video-service/app/pkg/probe"
+	"io"
+	"mime"
+	"path"
+	"strings"
+)
+
+var contentTypes = map[string]string{
+	probe.FormatMP4:  "video/mp4",
+	probe.FormatMOV:  "video/quicktime",
+	probe.FormatMKV:  "video/x-matroska",
+	probe.FormatWebM: "video/webm",
+	probe.FormatAVI:  "video/x-msvideo",
+}
+
+type VideoStream struct {
+	Key         string
+	ContentType string
+	Info        *storage.ObjectInfo
+}
+
+func GetVideoStream(id, userID int) (*VideoStream, error) {
+	video, err := mysql.GetConnection().GetInfoVideoById(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if video.Status == string(models.StatusDeleted) {
+		return nil, mysql.ErrVideoNotFound
+	}
+
+	info, err := storage.GetBackend().Stat(video.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VideoStream{
+		Key:         video.FilePath,
+		ContentType: contentTypeForContainer(video.Container),
+		Info:        info,
+	}, nil
+}
+
+func contentTypeForContainer(container string) string {
+	if contentType, ok := contentTypes[container]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func contentTypeForKey(key string) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	case ".mpd":
+		return "application/dash+xml"
+	case ".m4s":
+		return "video/iso.segment"
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return contentTypeForContainer(strings.TrimPrefix(strings.ToLower(path.Ext(key)), "."))
+}
+
+func (v *VideoStream) Open(offset, length int64) (io.ReadCloser, error) {
+	return storage.GetBackend().Get(v.Key, offset, length)
+}
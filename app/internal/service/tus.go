@@ -0,0 +1,320 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/Dimoonevs/video-service/app/pkg/lib"
+	"github.com/Dimoonevs/video-service/app/pkg/probe"
+	"github.com/sirupsen/logrus"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const partSuffix = ".part"
+
+var (
+	tusDir           = flag.String("tusDir", "", "directory for in-progress resumable uploads, defaults to pathToSave/.tus/")
+	tusUploadExpiry  = flag.Duration("tusUploadExpiry", 24*time.Hour, "how long an unfinished resumable upload is kept without progress")
+	tusSweepInterval = flag.Duration("tusSweepInterval", time.Hour, "how often expired resumable uploads are removed")
+
+	ErrUploadTooLarge    = errors.New("upload exceeds maximum size")
+	ErrUploadOffset      = errors.New("upload offset mismatch")
+	ErrUploadComplete    = errors.New("upload is already complete")
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	ErrRenditionNotFound = errors.New("rendition not found")
+
+	// uploadLocks serialize the requests for one upload within this process. With several instances,
+	// requests for an upload must be routed to the same one, e.g. by hashing the upload path.
+	uploadLocks sync.Map
+	sweeperOnce sync.Once
+)
+
+func CreateUpload(userID int, filename string, length int64, isStream bool) (*models.Upload, error) {
+	if length < 0 || length > *maxUploadSize {
+		return nil, ErrUploadTooLarge
+	}
+	id, err := lib.RandomID()
+	if err != nil {
+		return nil, err
+	}
+	savePath, err := buildSavePath(filename)
+	if err != nil {
+		return nil, err
+	}
+	upload := &models.Upload{
+		Id:        id,
+		UserId:    userID,
+		FileName:  filename,
+		FilePath:  savePath,
+		IsStream:  isStream,
+		Length:    length,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if err = os.MkdirAll(stagingDir(), os.ModePerm); err != nil {
+		return nil, err
+	}
+	part, err := os.Create(partPath(upload.Id))
+	if err != nil {
+		return nil, err
+	}
+	part.Close()
+
+	if err = mysql.GetConnection().CreateUpload(upload); err != nil {
+		os.Remove(partPath(upload.Id))
+		return nil, err
+	}
+
+	if length == 0 {
+		if err = completeUpload(upload); err != nil {
+			return nil, err
+		}
+	}
+	return upload, nil
+}
+
+func MaxUploadSize() int64 {
+	return *maxUploadSize
+}
+
+func GetUpload(id string, userID int) (*models.Upload, error) {
+	return mysql.GetConnection().GetUpload(id, userID)
+}
+
+// UploadExpiresAt is when an unfinished upload is removed unless it makes progress before.
+func UploadExpiresAt(upload *models.Upload) time.Time {
+	return upload.UpdatedAt.Add(*tusUploadExpiry)
+}
+
+func WriteUploadChunk(id string, userID int, offset int64, body io.Reader) (*models.Upload, error) {
+	unlock := lockUpload(id)
+	defer unlock()
+
+	upload, err := mysql.GetConnection().GetUpload(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.FileId != 0 {
+		return upload, ErrUploadComplete
+	}
+	if upload.Offset != offset {
+		return upload, ErrUploadOffset
+	}
+
+	part, err := os.OpenFile(partPath(upload.Id), os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer part.Close()
+
+	if _, err = part.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Whatever arrived before a dropped connection is kept so the client can resume from it.
+	written, copyErr := io.Copy(part, io.LimitReader(body, upload.Length-offset))
+	upload.Offset += written
+	if err = mysql.GetConnection().SetUploadOffset(upload.Id, upload.Offset); err != nil {
+		return nil, err
+	}
+	if written > 0 {
+		upload.UpdatedAt = time.Now().UTC()
+	}
+	if copyErr != nil {
+		return upload, fmt.Errorf("failed to write chunk: %w", copyErr)
+	}
+
+	if upload.Offset == upload.Length {
+		if err = part.Close(); err != nil {
+			return nil, err
+		}
+		if err = completeUpload(upload); err != nil {
+			return nil, err
+		}
+	}
+	return upload, nil
+}
+
+func TerminateUpload(id string, userID int) error {
+	unlock := lockUpload(id)
+	defer unlock()
+
+	upload, err := mysql.GetConnection().GetUpload(id, userID)
+	if err != nil {
+		return err
+	}
+	if upload.FileId == 0 {
+		if err = os.Remove(partPath(upload.Id)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err = mysql.GetConnection().DeleteUpload(upload.Id, userID); err != nil {
+		return err
+	}
+	uploadLocks.Delete(upload.Id)
+	return nil
+}
+
+// completeUpload registers the finished upload and leaves storing it to finishUpload, so the client's
+// last request does not wait for a copy of the whole file.
+func completeUpload(upload *models.Upload) error {
+	part, err := os.Open(partPath(upload.Id))
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+
+	container, err := detectContainer(part, upload.Length)
+	if err != nil {
+		discardUpload(upload)
+		return err
+	}
+
+	filesID, err := mysql.GetConnection().CompleteUpload(upload)
+	if err != nil {
+		// The staged file stays for a retry unless the file can never be registered.
+		if errors.Is(err, mysql.ErrDuplicateFile) {
+			discardUpload(upload)
+		}
+		return err
+	}
+	upload.FileId = filesID
+	uploadLocks.Delete(upload.Id)
+	reindexVideo(filesID)
+	if err = mysql.GetConnection().SetFileContainer(filesID, container.Format, container.Brand); err != nil {
+		logrus.Errorf("failed to store container of file %s: %v", upload.FileName, err)
+	}
+
+	go finishUpload(*upload, container)
+	logrus.Infof("resumable upload %s of file %s completed", upload.Id, upload.FileName)
+	return nil
+}
+
+// finishUpload moves the staged file of a registered upload into storage and queues it for conversion.
+// The file stays in loading status until then.
+func finishUpload(upload models.Upload, container *probe.Container) {
+	staged := partPath(upload.Id)
+	part, err := os.Open(staged)
+	if err != nil {
+		logrus.Errorf("failed to open staged upload %s: %v", upload.Id, err)
+		setStatus(upload.FileId, models.StatusLoadError, "failed to read uploaded file")
+		return
+	}
+	defer part.Close()
+
+	storeVideoMetadata(upload.FileId, part, upload.Length, container)
+	size, checksum, err := storeStagedUpload(part, upload.FilePath)
+	if err != nil {
+		logrus.Errorf("failed to store resumable upload %s: %v", upload.Id, err)
+		if delErr := storage.GetBackend().Delete(upload.FilePath); delErr != nil && !errors.Is(delErr, storage.ErrNotFound) {
+			logrus.Errorf("failed to delete partly stored file %s: %v", upload.FilePath, delErr)
+		}
+		setStatus(upload.FileId, models.StatusLoadError, "failed to save file")
+	} else {
+		if err = mysql.GetConnection().SetFileChecksum(upload.FileId, size, checksum); err != nil {
+			logrus.Errorf("failed to store checksum of file %s: %v", upload.FileName, err)
+		}
+		setStatus(upload.FileId, models.StatusNoConv, "resumable upload completed")
+		enqueueConversion(upload.FileId)
+	}
+	if err = os.Remove(staged); err != nil && !os.IsNotExist(err) {
+		logrus.Errorf("failed to remove staged upload %s: %v", upload.Id, err)
+	}
+}
+
+// storeStagedUpload renames the staged file into place when the backend allows it, so the bytes are
+// only read once more for the checksum, and copies it otherwise.
+func storeStagedUpload(part *os.File, key string) (int64, string, error) {
+	if mover, ok := storage.GetBackend().(storage.FileMover); ok {
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, io.NewSectionReader(part, 0, math.MaxInt64))
+		if err != nil {
+			return 0, "", err
+		}
+		if err = mover.MoveFile(part.Name(), key); err == nil {
+			return size, hex.EncodeToString(hasher.Sum(nil)), nil
+		}
+		logrus.Warnf("failed to move %s into storage, copying it instead: %v", part.Name(), err)
+	}
+	return saveToStorage(io.NewSectionReader(part, 0, math.MaxInt64), key)
+}
+
+// discardUpload drops an upload that can never complete, together with its staged file.
+func discardUpload(upload *models.Upload) {
+	if err := os.Remove(partPath(upload.Id)); err != nil && !os.IsNotExist(err) {
+		logrus.Errorf("failed to remove staged upload %s: %v", upload.Id, err)
+	}
+	if err := mysql.GetConnection().DeleteUpload(upload.Id, upload.UserId); err != nil {
+		logrus.Errorf("failed to delete rejected upload %s: %v", upload.Id, err)
+	}
+	uploadLocks.Delete(upload.Id)
+}
+
+// StartUploadSweeper periodically removes unfinished uploads that made no progress for tusUploadExpiry.
+func StartUploadSweeper() {
+	sweeperOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(*tusSweepInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				sweepExpiredUploads()
+			}
+		}()
+	})
+}
+
+func sweepExpiredUploads() {
+	before := time.Now().Add(-*tusUploadExpiry)
+	ids, err := mysql.GetConnection().GetExpiredUploads(before)
+	if err != nil {
+		logrus.Errorf("failed to list expired uploads: %v", err)
+		return
+	}
+
+	var removed int
+	for _, id := range ids {
+		unlock := lockUpload(id)
+		// A chunk may have arrived since the listing, so the row decides.
+		deleted, err := mysql.GetConnection().DeleteExpiredUpload(id, before)
+		if err != nil {
+			logrus.Errorf("failed to delete expired upload %s: %v", id, err)
+		} else if deleted {
+			if err = os.Remove(partPath(id)); err != nil && !os.IsNotExist(err) {
+				logrus.Errorf("failed to remove staged upload %s: %v", id, err)
+			}
+			uploadLocks.Delete(id)
+			removed++
+		}
+		unlock()
+	}
+	if removed > 0 {
+		logrus.Infof("removed %d expired resumable uploads", removed)
+	}
+}
+
+func stagingDir() string {
+	if *tusDir != "" {
+		return *tusDir
+	}
+	return *pathToSave + ".tus/"
+}
+
+func partPath(uploadID string) string {
+	return filepath.Join(stagingDir(), uploadID+partSuffix)
+}
+
+func lockUpload(id string) func() {
+	mu, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
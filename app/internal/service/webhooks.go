@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/pkg/lib"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	maxWebhookURLLength    = 2048
+	maxWebhookDeliveries   = 100
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+var (
+	webhookMaxPerUser      = flag.Int("webhookMaxPerUser", 20, "webhooks a user may register")
+	webhookMaxAttempts     = flag.Int("webhookMaxAttempts", 8, "delivery attempts before a webhook delivery fails")
+	webhookRetryBaseDelay  = flag.Duration("webhookRetryBaseDelay", 30*time.Second, "delay before the first redelivery, doubled on every attempt")
+	webhookRetryMaxDelay   = flag.Duration("webhookRetryMaxDelay", 6*time.Hour, "upper bound of the redelivery delay")
+	webhookTimeout         = flag.Duration("webhookTimeout", 10*time.Second, "timeout of a single webhook request")
+	webhookPollInterval    = flag.Duration("webhookPollInterval", 2*time.Second, "how often pending webhook deliveries are picked up")
+	webhookBatchSize       = flag.Int("webhookBatchSize", 20, "webhook deliveries sent concurrently per poll")
+	webhookAllowPrivateIPs = flag.Bool("webhookAllowPrivateIPs", false, "allow webhooks to target loopback and private networks")
+
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	ErrWebhookLimit   = errors.New("webhook limit reached")
+
+	webhookClient     *http.Client
+	webhookClientOnce sync.Once
+	dispatcherOnce    sync.Once
+)
+
+// CreateWebhook registers a hook with a fresh secret; the secret is only returned here and on rotation.
+func CreateWebhook(userID int, req *models.WebhookReq) (*models.Webhook, error) {
+	count, err := mysql.GetConnection().CountWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= *webhookMaxPerUser {
+		return nil, fmt.Errorf("%w: at most %d webhooks per user", ErrWebhookLimit, *webhookMaxPerUser)
+	}
+
+	hook := &models.Webhook{UserId: userID, Active: true, Events: []string{}}
+	if req.URL == nil {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidWebhook)
+	}
+	if err = applyWebhookReq(hook, req); err != nil {
+		return nil, err
+	}
+	if hook.Secret, err = lib.RandomID(); err != nil {
+		return nil, err
+	}
+	if err = mysql.GetConnection().CreateWebhook(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func GetWebhooks(userID int) ([]*models.Webhook, error) {
+	hooks, err := mysql.GetConnection().GetWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	return hooks, nil
+}
+
+func GetWebhook(id, userID int) (*models.Webhook, error) {
+	hook, err := mysql.GetConnection().GetWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+func UpdateWebhook(id, userID int, req *models.WebhookReq) (*models.Webhook, error) {
+	hook, err := mysql.GetConnection().GetWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = applyWebhookReq(hook, req); err != nil {
+		return nil, err
+	}
+
+	secret := hook.Secret
+	if req.RotateSecret {
+		if secret, err = lib.RandomID(); err != nil {
+			return nil, err
+		}
+		hook.Secret = secret
+	}
+	if err = mysql.GetConnection().UpdateWebhook(hook); err != nil {
+		return nil, err
+	}
+	if !req.RotateSecret {
+		hook.Secret = ""
+	}
+	return hook, nil
+}
+
+func DeleteWebhook(id, userID int) error {
+	return mysql.GetConnection().DeleteWebhook(id, userID)
+}
+
+func GetWebhookDeliveries(id, userID, limit int) ([]*models.WebhookDelivery, error) {
+	if _, err := mysql.GetConnection().GetWebhook(id, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxWebhookDeliveries {
+		limit = maxWebhookDeliveries
+	}
+	return mysql.GetConnection().GetWebhookDeliveries(id, limit)
+}
+
+// RedeliverWebhook queues a new attempt of an earlier delivery and returns the new delivery id.
+func RedeliverWebhook(id, userID int, deliveryID int64) (int64, error) {
+	if _, err := mysql.GetConnection().GetWebhook(id, userID); err != nil {
+		return 0, err
+	}
+	return mysql.GetConnection().RedeliverWebhookDelivery(id, deliveryID)
+}
+
+func applyWebhookReq(hook *models.Webhook, req *models.WebhookReq) error {
+	if req.URL != nil {
+		u, err := url.Parse(*req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(*req.URL) > maxWebhookURLLength {
+			return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+		}
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		events := []string{}
+		for _, event := range req.Events {
+			if !slices.Contains(models.WebhookEvents, event) {
+				return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+			}
+			if !slices.Contains(events, event) {
+				events = append(events, event)
+			}
+		}
+		hook.Events = events
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	return nil
+}
+
+// StartWebhookDispatcher sends pending webhook deliveries in the background.
+func StartWebhookDispatcher() {
+	dispatcherOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(*webhookPollInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				dispatchWebhooks()
+			}
+		}()
+	})
+}
+
+func dispatchWebhooks() {
+	for {
+		lease := int((*webhookTimeout).Seconds()) * 2
+		deliveries, err := mysql.GetConnection().ClaimWebhookDeliveries(*webhookBatchSize, lease)
+		if err != nil {
+			logrus.Errorf("failed to claim webhook deliveries: %v", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				deliverWebhook(delivery)
+			}(delivery)
+		}
+		wg.Wait()
+	}
+}
+
+func deliverWebhook(delivery *models.WebhookDelivery) {
+	responseStatus, err := sendWebhook(delivery)
+	status, delay, lastError := models.DeliverySucceeded, 0, ""
+	if err != nil {
+		lastError = err.Error()
+		status = models.DeliveryFailed
+		if delivery.Attempts+1 < *webhookMaxAttempts {
+			status = models.DeliveryPending
+			delay = backoffSeconds(*webhookRetryBaseDelay, *webhookRetryMaxDelay, delivery.Attempts+1)
+		}
+		logrus.Warnf("webhook delivery %d to %s failed: %v", delivery.Id, delivery.URL, err)
+	}
+	if err = mysql.GetConnection().SetWebhookDeliveryResult(delivery.Id, status, responseStatus, lastError, delay); err != nil {
+		logrus.Errorf("failed to record webhook delivery %d: %v", delivery.Id, err)
+	}
+}
+
+func sendWebhook(delivery *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "video-service-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+SignWebhookPayload(delivery.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := getWebhookClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload is HMAC-SHA256 over "<timestamp>.<body>" keyed with the hook's secret, hex encoded.
+// Receivers recompute it and compare with the X-Webhook-Signature header, rejecting stale timestamps.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func getWebhookClient() *http.Client {
+	webhookClientOnce.Do(func() {
+		dialer := &net.Dialer{Timeout: *webhookTimeout}
+		if !*webhookAllowPrivateIPs {
+			dialer.Control = rejectPrivateAddress
+		}
+		webhookClient = &http.Client{
+			Timeout: *webhookTimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, addr)
+				},
+				MaxIdleConnsPerHost: 4,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	})
+	return webhookClient
+}
+
+// rejectPrivateAddress runs after DNS resolution, so hostnames pointing into internal networks are refused too.
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("webhook target %s is not allowed", host)
+	}
+	return nil
+}
@@ -1,8 +1,9 @@
 package lib
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"flag"
-	"path/filepath"
 	"strings"
 )
 
@@ -11,14 +12,18 @@ var (
 	publicHost    = flag.String("publicHost", "http://file.your-video-service.pp.ua/video/service/", "public host")
 )
 
-func IsMP4(filename string) bool {
-	return filepath.Ext(filename) == ".mp4"
-}
-
 func GetVideoLocalLink(link string) string {
-	return strings.ReplaceAll(link, *staticRootDir, *publicHost)
+	return strings.ReplaceAll(link, *publicHost, *staticRootDir)
 }
 
 func GetVideoPublicLink(link string) string {
 	return strings.ReplaceAll(link, *staticRootDir, *publicHost)
 }
+
+func RandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,155 @@
+package manifest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const dashProfile = "urn:mpeg:dash:profile:isoff-main:2011"
+
+type mpd struct {
+	XMLName                   xml.Name `xml:"MPD"`
+	Xmlns                     string   `xml:"xmlns,attr"`
+	Profiles                  string   `xml:"profiles,attr"`
+	Type                      string   `xml:"type,attr"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr,omitempty"`
+	MinBufferTime             string   `xml:"minBufferTime,attr"`
+	Period                    period   `xml:"Period"`
+}
+
+type period struct {
+	ID             string          `xml:"id,attr"`
+	AdaptationSets []adaptationSet `xml:"AdaptationSet"`
+}
+
+type adaptationSet struct {
+	ID               int              `xml:"id,attr"`
+	MimeType         string           `xml:"mimeType,attr"`
+	SegmentAlignment bool             `xml:"segmentAlignment,attr"`
+	StartWithSAP     int              `xml:"startWithSAP,attr"`
+	MaxWidth         int              `xml:"maxWidth,attr,omitempty"`
+	MaxHeight        int              `xml:"maxHeight,attr,omitempty"`
+	Representations  []representation `xml:"Representation"`
+}
+
+type representation struct {
+	ID          string       `xml:"id,attr"`
+	Bandwidth   int64        `xml:"bandwidth,attr"`
+	Width       int          `xml:"width,attr,omitempty"`
+	Height      int          `xml:"height,attr,omitempty"`
+	Codecs      string       `xml:"codecs,attr,omitempty"`
+	BaseURL     string       `xml:"BaseURL,omitempty"`
+	SegmentList *segmentList `xml:"SegmentList,omitempty"`
+}
+
+type segmentList struct {
+	Timescale       int          `xml:"timescale,attr"`
+	Initialization  *initSegment `xml:"Initialization,omitempty"`
+	SegmentTimeline []timelineS  `xml:"SegmentTimeline>S"`
+	SegmentURLs     []segmentURL `xml:"SegmentURL"`
+}
+
+type initSegment struct {
+	SourceURL string `xml:"sourceURL,attr"`
+}
+
+type timelineS struct {
+	D int64 `xml:"d,attr"`
+}
+
+type segmentURL struct {
+	Media string `xml:"media,attr"`
+}
+
+// DASHManifest builds a static MPD with one AdaptationSet per mime type. Variants with segments are
+// described with a SegmentList, the rest are single-file representations addressed by BaseURL.
+func DASHManifest(variants []Variant, durationSeconds float64) (string, error) {
+	sorted := append([]Variant(nil), variants...)
+	sortVariants(sorted)
+
+	doc := mpd{
+		Xmlns:         "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:      dashProfile,
+		Type:          "static",
+		MinBufferTime: "PT2S",
+		Period:        period{ID: "0"},
+	}
+	if durationSeconds > 0 {
+		doc.MediaPresentationDuration = isoDuration(durationSeconds)
+	}
+
+	sets := map[string]int{}
+	for _, v := range sorted {
+		mimeType := v.MimeType
+		if mimeType == "" {
+			mimeType = "video/mp4"
+		}
+		index, ok := sets[mimeType]
+		if !ok {
+			index = len(doc.Period.AdaptationSets)
+			sets[mimeType] = index
+			doc.Period.AdaptationSets = append(doc.Period.AdaptationSets, adaptationSet{
+				ID:               index,
+				MimeType:         mimeType,
+				SegmentAlignment: true,
+				StartWithSAP:     1,
+			})
+		}
+
+		set := &doc.Period.AdaptationSets[index]
+		set.MaxWidth = max(set.MaxWidth, v.Width)
+		set.MaxHeight = max(set.MaxHeight, v.Height)
+		set.Representations = append(set.Representations, dashRepresentation(v))
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(out) + "\n", nil
+}
+
+func dashRepresentation(v Variant) representation {
+	rep := representation{
+		ID:        v.Name,
+		Bandwidth: v.Bandwidth,
+		Width:     v.Width,
+		Height:    v.Height,
+		Codecs:    v.Codecs,
+	}
+	if len(v.Segments) == 0 {
+		rep.BaseURL = v.URI
+		return rep
+	}
+
+	list := &segmentList{Timescale: 1000}
+	if v.InitURI != "" {
+		list.Initialization = &initSegment{SourceURL: v.InitURI}
+	}
+	for _, s := range v.Segments {
+		list.SegmentTimeline = append(list.SegmentTimeline, timelineS{D: int64(math.Round(s.Duration * 1000))})
+		list.SegmentURLs = append(list.SegmentURLs, segmentURL{Media: s.URI})
+	}
+	rep.SegmentList = list
+	return rep
+}
+
+// isoDuration formats seconds as an xs:duration such as PT1H2M3.500S.
+func isoDuration(seconds float64) string {
+	var b strings.Builder
+	b.WriteString("PT")
+	hours := int(seconds / 3600)
+	seconds -= float64(hours * 3600)
+	minutes := int(seconds / 60)
+	seconds -= float64(minutes * 60)
+	if hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+	}
+	fmt.Fprintf(&b, "%.3fS", seconds)
+	return b.String()
+}
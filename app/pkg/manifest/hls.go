@@ -0,0 +1,52 @@
+package manifest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+func HLSMaster(variants []Variant) string {
+	sorted := append([]Variant(nil), variants...)
+	sortVariants(sorted)
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, v := range sorted {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d", v.Bandwidth)
+		if v.Width > 0 && v.Height > 0 {
+			fmt.Fprintf(&b, ",RESOLUTION=%dx%d", v.Width, v.Height)
+		}
+		if v.Codecs != "" {
+			fmt.Fprintf(&b, ",CODECS=\"%s\"", v.Codecs)
+		}
+		b.WriteString("\n" + v.URI + "\n")
+	}
+	return b.String()
+}
+
+// HLSMedia builds a VOD media playlist. A non-empty initURI is the fMP4 initialization segment,
+// announced with EXT-X-MAP.
+func HLSMedia(initURI string, segments []Segment) string {
+	target := 0.0
+	for _, s := range segments {
+		target = math.Max(target, s.Duration)
+	}
+
+	version := 3
+	if initURI != "" {
+		version = 7
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:%d\n", version)
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target)))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+	if initURI != "" {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", initURI)
+	}
+	for _, s := range segments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", s.Duration, s.URI)
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package manifest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Variant struct {
+	Name      string
+	URI       string
+	Bandwidth int64
+	Width     int
+	Height    int
+	Codecs    string
+	MimeType  string
+	InitURI   string
+	Segments  []Segment
+}
+
+type Segment struct {
+	URI      string
+	Duration float64
+}
+
+// bandwidthLadder is used when the converter did not report a bitrate for a rendition.
+var bandwidthLadder = []struct {
+	height    int
+	bandwidth int64
+}{
+	{240, 400_000},
+	{360, 800_000},
+	{480, 1_400_000},
+	{720, 2_800_000},
+	{1080, 5_000_000},
+	{1440, 8_000_000},
+	{2160, 16_000_000},
+}
+
+// ParseResolution accepts "1280x720", "720p" or "720" and assumes 16:9 when only the height is known.
+func ParseResolution(resolution string) (int, int) {
+	resolution = strings.ToLower(strings.TrimSpace(resolution))
+	if w, h, ok := strings.Cut(resolution, "x"); ok {
+		width, errW := strconv.Atoi(w)
+		height, errH := strconv.Atoi(h)
+		if errW == nil && errH == nil {
+			return width, height
+		}
+		return 0, 0
+	}
+
+	height, err := strconv.Atoi(strings.TrimSuffix(resolution, "p"))
+	if err != nil {
+		return 0, 0
+	}
+	width := (height*16/9 + 1) &^ 1
+	return width, height
+}
+
+func EstimateBandwidth(height int) int64 {
+	for _, step := range bandwidthLadder {
+		if height <= step.height {
+			return step.bandwidth
+		}
+	}
+	return bandwidthLadder[len(bandwidthLadder)-1].bandwidth
+}
+
+func sortVariants(variants []Variant) {
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Bandwidth < variants[j].Bandwidth })
+}
@@ -0,0 +1,115 @@
+package probe
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+const maxTopLevelBoxes = 1024
+
+type box struct {
+	Type       string
+	Offset     int64
+	HeaderSize int64
+	Size       int64
+}
+
+func (b box) dataOffset() int64 {
+	return b.Offset + b.HeaderSize
+}
+
+func (b box) end() int64 {
+	return b.Offset + b.Size
+}
+
+func readBox(r io.ReaderAt, offset, limit int64) (box, error) {
+	var header [16]byte
+	if limit-offset < 8 {
+		return box{}, ErrUnknownContainer
+	}
+	if _, err := r.ReadAt(header[:8], offset); err != nil {
+		return box{}, ErrUnknownContainer
+	}
+
+	b := box{
+		Type:       string(header[4:8]),
+		Offset:     offset,
+		HeaderSize: 8,
+		Size:       int64(binary.BigEndian.Uint32(header[:4])),
+	}
+	if !isBoxType(b.Type) {
+		return box{}, ErrUnknownContainer
+	}
+
+	switch b.Size {
+	case 0:
+		b.Size = limit - offset
+	case 1:
+		if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+			return box{}, ErrUnknownContainer
+		}
+		b.HeaderSize = 16
+		b.Size = int64(binary.BigEndian.Uint64(header[8:16]))
+	}
+	if b.Size < b.HeaderSize || b.Size > limit-offset {
+		return box{}, ErrUnknownContainer
+	}
+	return b, nil
+}
+
+func isBoxType(t string) bool {
+	for i := 0; i < len(t); i++ {
+		if t[i] < 0x20 || t[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func detectISOBMFF(r io.ReaderAt, size int64) (*Container, error) {
+	var container *Container
+	var hasMoov bool
+
+	offset := int64(0)
+	for i := 0; offset < size && i < maxTopLevelBoxes; i++ {
+		b, err := readBox(r, offset, size)
+		if err != nil {
+			return nil, err
+		}
+
+		switch b.Type {
+		case "ftyp":
+			if container != nil || hasMoov {
+				return nil, ErrUnknownContainer
+			}
+			var brand [4]byte
+			if b.Size-b.HeaderSize < 4 {
+				return nil, ErrUnknownContainer
+			}
+			if _, err = r.ReadAt(brand[:], b.dataOffset()); err != nil {
+				return nil, ErrUnknownContainer
+			}
+			container = &Container{Format: formatForBrand(string(brand[:])), Brand: strings.TrimSpace(string(brand[:]))}
+		case "moov":
+			hasMoov = true
+		}
+		offset = b.end()
+	}
+
+	if !hasMoov {
+		return nil, ErrUnknownContainer
+	}
+	if container == nil {
+		// QuickTime files written before ftyp was introduced start directly with moov/mdat/wide.
+		container = &Container{Format: FormatMOV, Brand: "qt"}
+	}
+	return container, nil
+}
+
+func formatForBrand(brand string) string {
+	if brand == "qt  " {
+		return FormatMOV
+	}
+	return FormatMP4
+}
@@ -0,0 +1,119 @@
+package probe
+
+import (
+	"io"
+	"strings"
+)
+
+const (
+	idEBML    = 0x1A45DFA3
+	idDocType = 0x4282
+	idSegment = 0x18538067
+)
+
+type element struct {
+	ID         uint32
+	Offset     int64
+	HeaderSize int64
+	Size       int64
+}
+
+func (e element) dataOffset() int64 {
+	return e.Offset + e.HeaderSize
+}
+
+func (e element) end() int64 {
+	return e.Offset + e.HeaderSize + e.Size
+}
+
+func readElement(r io.ReaderAt, offset, limit int64) (element, error) {
+	id, idLen, err := readVint(r, offset, 4, true)
+	if err != nil {
+		return element{}, err
+	}
+	size, sizeLen, err := readVint(r, offset+int64(idLen), 8, false)
+	if err != nil {
+		return element{}, err
+	}
+
+	e := element{
+		ID:         uint32(id),
+		Offset:     offset,
+		HeaderSize: int64(idLen + sizeLen),
+		Size:       int64(size),
+	}
+	// All size bits set means "unknown size", which live-recorded segments use.
+	if size == 1<<(7*uint(sizeLen))-1 || e.Size > limit-e.dataOffset() {
+		e.Size = limit - e.dataOffset()
+	}
+	if e.Size < 0 {
+		return element{}, ErrUnknownContainer
+	}
+	return e, nil
+}
+
+func readVint(r io.ReaderAt, offset int64, maxLen int, keepMarker bool) (uint64, int, error) {
+	var buf [8]byte
+	if _, err := r.ReadAt(buf[:1], offset); err != nil {
+		return 0, 0, ErrUnknownContainer
+	}
+
+	length := 1
+	for mask := byte(0x80); length <= maxLen && buf[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > maxLen {
+		return 0, 0, ErrUnknownContainer
+	}
+	if length > 1 {
+		if _, err := r.ReadAt(buf[1:length], offset+1); err != nil {
+			return 0, 0, ErrUnknownContainer
+		}
+	}
+
+	value := uint64(buf[0])
+	if !keepMarker {
+		value &= uint64(0xFF >> length)
+	}
+	for i := 1; i < length; i++ {
+		value = value<<8 | uint64(buf[i])
+	}
+	return value, length, nil
+}
+
+func detectMatroska(r io.ReaderAt, size int64) (*Container, error) {
+	header, err := readElement(r, 0, size)
+	if err != nil || header.ID != idEBML {
+		return nil, ErrUnknownContainer
+	}
+
+	var docType string
+	for offset := header.dataOffset(); offset < header.end(); {
+		child, err := readElement(r, offset, header.end())
+		if err != nil {
+			return nil, err
+		}
+		if child.ID == idDocType && child.Size <= 64 {
+			value := make([]byte, child.Size)
+			if _, err = r.ReadAt(value, child.dataOffset()); err != nil {
+				return nil, ErrUnknownContainer
+			}
+			docType = strings.TrimRight(string(value), "\x00")
+		}
+		offset = child.end()
+	}
+
+	segment, err := readElement(r, header.end(), size)
+	if err != nil || segment.ID != idSegment {
+		return nil, ErrUnknownContainer
+	}
+
+	switch docType {
+	case "matroska":
+		return &Container{Format: FormatMKV, Brand: docType}, nil
+	case "webm":
+		return &Container{Format: FormatWebM, Brand: docType}, nil
+	default:
+		return nil, ErrUnknownContainer
+	}
+}
@@ -0,0 +1,269 @@
+package probe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+type Metadata struct {
+	DurationMs int64
+	Width      int
+	Height     int
+	FrameRate  float64
+	VideoCodec string
+	AudioCodec string
+	Bitrate    int64
+	Rotation   int
+}
+
+type track struct {
+	handler     string
+	codec       string
+	width       int
+	height      int
+	rotation    int
+	timescale   uint32
+	duration    uint64
+	sampleCount uint64
+}
+
+func ReadMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	moov, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{}
+	var tracks []*track
+	err = walkBoxes(r, moov.dataOffset(), moov.end(), func(b box) error {
+		switch b.Type {
+		case "mvhd":
+			timescale, duration, err := readMediaHeader(r, b)
+			if err != nil {
+				return err
+			}
+			if timescale > 0 {
+				meta.DurationMs = int64(duration * 1000 / uint64(timescale))
+			}
+		case "trak":
+			t, err := readTrack(r, b)
+			if err != nil {
+				return err
+			}
+			tracks = append(tracks, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tracks {
+		switch t.handler {
+		case "vide":
+			if meta.VideoCodec != "" {
+				continue
+			}
+			meta.VideoCodec = t.codec
+			meta.Width, meta.Height = t.width, t.height
+			meta.Rotation = t.rotation
+			if t.duration > 0 && t.timescale > 0 {
+				meta.FrameRate = float64(t.sampleCount) * float64(t.timescale) / float64(t.duration)
+			}
+		case "soun":
+			if meta.AudioCodec == "" {
+				meta.AudioCodec = t.codec
+			}
+		}
+	}
+
+	if meta.DurationMs > 0 {
+		meta.Bitrate = size * 8 * 1000 / meta.DurationMs
+	}
+	return meta, nil
+}
+
+func readTrack(r io.ReaderAt, trak box) (*track, error) {
+	t := &track{}
+	err := walkBoxes(r, trak.dataOffset(), trak.end(), func(b box) error {
+		switch b.Type {
+		case "tkhd":
+			return readTrackHeader(r, b, t)
+		case "mdia":
+			return walkBoxes(r, b.dataOffset(), b.end(), func(child box) error {
+				return readTrackChild(r, child, t)
+			})
+		}
+		return nil
+	})
+	return t, err
+}
+
+func readTrackChild(r io.ReaderAt, b box, t *track) error {
+	switch b.Type {
+	case "minf", "stbl":
+		return walkBoxes(r, b.dataOffset(), b.end(), func(child box) error {
+			return readTrackChild(r, child, t)
+		})
+	case "mdhd":
+		timescale, duration, err := readMediaHeader(r, b)
+		if err != nil {
+			return err
+		}
+		t.timescale, t.duration = timescale, duration
+	case "hdlr":
+		var handler [4]byte
+		if _, err := r.ReadAt(handler[:], b.dataOffset()+8); err != nil {
+			return ErrUnknownContainer
+		}
+		t.handler = string(handler[:])
+	case "stsd":
+		return readSampleDescription(r, b, t)
+	case "stts":
+		return readTimeToSample(r, b, t)
+	}
+	return nil
+}
+
+// readMediaHeader reads timescale and duration from mvhd/mdhd, which share a layout
+// after the version-dependent creation and modification times.
+func readMediaHeader(r io.ReaderAt, b box) (uint32, uint64, error) {
+	var version [1]byte
+	if _, err := r.ReadAt(version[:], b.dataOffset()); err != nil {
+		return 0, 0, ErrUnknownContainer
+	}
+
+	var buf [12]byte
+	if version[0] == 1 {
+		if b.dataOffset()+4+16+12 > b.end() {
+			return 0, 0, ErrUnknownContainer
+		}
+		if _, err := r.ReadAt(buf[:12], b.dataOffset()+4+16); err != nil {
+			return 0, 0, ErrUnknownContainer
+		}
+		return binary.BigEndian.Uint32(buf[0:4]), binary.BigEndian.Uint64(buf[4:12]), nil
+	}
+	if b.dataOffset()+4+8+8 > b.end() {
+		return 0, 0, ErrUnknownContainer
+	}
+	if _, err := r.ReadAt(buf[:8], b.dataOffset()+4+8); err != nil {
+		return 0, 0, ErrUnknownContainer
+	}
+	return binary.BigEndian.Uint32(buf[0:4]), uint64(binary.BigEndian.Uint32(buf[4:8])), nil
+}
+
+func readTrackHeader(r io.ReaderAt, b box, t *track) error {
+	var version [1]byte
+	if _, err := r.ReadAt(version[:], b.dataOffset()); err != nil {
+		return ErrUnknownContainer
+	}
+
+	// version, flags, times, track id, reserved and duration, then reserved, layer,
+	// alternate group, volume and reserved before the matrix.
+	offset := b.dataOffset() + 4 + 20 + 16
+	if version[0] == 1 {
+		offset = b.dataOffset() + 4 + 32 + 16
+	}
+
+	var buf [44]byte
+	if offset+int64(len(buf)) > b.end() {
+		return ErrUnknownContainer
+	}
+	if _, err := r.ReadAt(buf[:], offset); err != nil {
+		return ErrUnknownContainer
+	}
+	a := int32(binary.BigEndian.Uint32(buf[0:4]))
+	bm := int32(binary.BigEndian.Uint32(buf[4:8]))
+	c := int32(binary.BigEndian.Uint32(buf[12:16]))
+	d := int32(binary.BigEndian.Uint32(buf[16:20]))
+	t.rotation = matrixRotation(a, bm, c, d)
+	t.width = int(binary.BigEndian.Uint32(buf[36:40]) >> 16)
+	t.height = int(binary.BigEndian.Uint32(buf[40:44]) >> 16)
+	return nil
+}
+
+func matrixRotation(a, b, c, d int32) int {
+	const one = 1 << 16
+	switch {
+	case a == 0 && b == one && c == -one && d == 0:
+		return 90
+	case a == -one && b == 0 && c == 0 && d == -one:
+		return 180
+	case a == 0 && b == -one && c == one && d == 0:
+		return 270
+	default:
+		return 0
+	}
+}
+
+func readSampleDescription(r io.ReaderAt, b box, t *track) error {
+	entry, err := readBox(r, b.dataOffset()+8, b.end())
+	if err != nil {
+		return err
+	}
+	t.codec = strings.TrimSpace(entry.Type)
+
+	if t.width == 0 && t.height == 0 && entry.Size >= entry.HeaderSize+28 {
+		var dims [4]byte
+		if _, err = r.ReadAt(dims[:], entry.dataOffset()+24); err != nil {
+			return ErrUnknownContainer
+		}
+		t.width = int(binary.BigEndian.Uint16(dims[0:2]))
+		t.height = int(binary.BigEndian.Uint16(dims[2:4]))
+	}
+	return nil
+}
+
+func readTimeToSample(r io.ReaderAt, b box, t *track) error {
+	var count [4]byte
+	if _, err := r.ReadAt(count[:], b.dataOffset()+4); err != nil {
+		return ErrUnknownContainer
+	}
+	entries := int64(binary.BigEndian.Uint32(count[:]))
+	if b.dataOffset()+8+entries*8 > b.end() {
+		return ErrUnknownContainer
+	}
+
+	table := bufio.NewReader(io.NewSectionReader(r, b.dataOffset()+8, entries*8))
+	var entry [8]byte
+	for i := int64(0); i < entries; i++ {
+		if _, err := io.ReadFull(table, entry[:]); err != nil {
+			return ErrUnknownContainer
+		}
+		t.sampleCount += uint64(binary.BigEndian.Uint32(entry[0:4]))
+	}
+	return nil
+}
+
+func findBox(r io.ReaderAt, offset, limit int64, boxType string) (box, error) {
+	found := box{}
+	err := walkBoxes(r, offset, limit, func(b box) error {
+		if b.Type == boxType && found.Type == "" {
+			found = b
+		}
+		return nil
+	})
+	if err != nil {
+		return box{}, err
+	}
+	if found.Type == "" {
+		return box{}, ErrUnknownContainer
+	}
+	return found, nil
+}
+
+func walkBoxes(r io.ReaderAt, offset, limit int64, fn func(b box) error) error {
+	for i := 0; offset < limit && i < maxTopLevelBoxes; i++ {
+		b, err := readBox(r, offset, limit)
+		if err != nil {
+			return err
+		}
+		if err = fn(b); err != nil {
+			return err
+		}
+		offset = b.end()
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+const fixed1 = 1 << 16
+
+var (
+	identityMatrix = [9]int32{fixed1, 0, 0, 0, fixed1, 0, 0, 0, 1 << 30}
+	rotate90       = [9]int32{0, fixed1, 0, -fixed1, 0, 0, 0, 0, 1 << 30}
+	rotate180      = [9]int32{-fixed1, 0, 0, 0, -fixed1, 0, 0, 0, 1 << 30}
+	rotate270      = [9]int32{0, -fixed1, 0, fixed1, 0, 0, 0, 0, 1 << 30}
+)
+
+func fullBox(boxType string, version byte, payload []byte) []byte {
+	return isoBox(boxType, concat([]byte{version, 0, 0, 0}, payload))
+}
+
+// mediaHeader builds mvhd or mdhd; version 1 widens the times and the duration to 64 bits.
+func mediaHeader(boxType string, version byte, timescale uint32, duration uint64) []byte {
+	var b []byte
+	if version == 1 {
+		b = make([]byte, 16)
+		b = binary.BigEndian.AppendUint32(b, timescale)
+		b = binary.BigEndian.AppendUint64(b, duration)
+	} else {
+		b = make([]byte, 8)
+		b = binary.BigEndian.AppendUint32(b, timescale)
+		b = binary.BigEndian.AppendUint32(b, uint32(duration))
+	}
+	return fullBox(boxType, version, append(b, make([]byte, 4)...))
+}
+
+func tkhd(version byte, matrix [9]int32, width, height int) []byte {
+	// Times, track id, reserved and duration, then reserved, layer, alternate group, volume and reserved.
+	b := make([]byte, 20+16)
+	if version == 1 {
+		b = make([]byte, 32+16)
+	}
+	for _, v := range matrix {
+		b = binary.BigEndian.AppendUint32(b, uint32(v))
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(width)<<16)
+	b = binary.BigEndian.AppendUint32(b, uint32(height)<<16)
+	return fullBox("tkhd", version, b)
+}
+
+func hdlr(handler string) []byte {
+	return fullBox("hdlr", 0, concat(make([]byte, 4), []byte(handler), make([]byte, 12), []byte("handler\x00")))
+}
+
+// stsd holds a single sample entry; visual entries carry their dimensions 24 bytes into the entry.
+func stsd(codec string, width, height uint16) []byte {
+	entry := make([]byte, 24)
+	entry = binary.BigEndian.AppendUint16(entry, width)
+	entry = binary.BigEndian.AppendUint16(entry, height)
+	entry = append(entry, make([]byte, 50)...)
+	return fullBox("stsd", 0, concat(binary.BigEndian.AppendUint32(nil, 1), isoBox(codec, entry)))
+}
+
+func stts(entries ...[2]uint32) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(entries)))
+	for _, e := range entries {
+		b = binary.BigEndian.AppendUint32(b, e[0])
+		b = binary.BigEndian.AppendUint32(b, e[1])
+	}
+	return fullBox("stts", 0, b)
+}
+
+func trak(header, mdhd, handler, sampleTable []byte) []byte {
+	stbl := isoBox("stbl", sampleTable)
+	return isoBox("trak", concat(header, isoBox("mdia", concat(mdhd, handler, isoBox("minf", stbl)))))
+}
+
+func videoTrak(version byte, matrix [9]int32, width, height int, codec string) []byte {
+	return trak(
+		tkhd(version, matrix, width, height),
+		mediaHeader("mdhd", version, 30000, 300300),
+		hdlr("vide"),
+		concat(stsd(codec, 640, 480), stts([2]uint32{300, 1001})),
+	)
+}
+
+func audioTrak() []byte {
+	return trak(
+		tkhd(0, identityMatrix, 0, 0),
+		mediaHeader("mdhd", 0, 48000, 480480),
+		hdlr("soun"),
+		concat(stsd("mp4a", 0, 0), stts([2]uint32{470, 1024})),
+	)
+}
+
+func movie(mvhdVersion byte, traks ...[]byte) []byte {
+	moov := isoBox("moov", concat(append([][]byte{mediaHeader("mvhd", mvhdVersion, 1000, 10010)}, traks...)...))
+	return concat(ftyp("isom"), moov, isoBox("mdat", make([]byte, 1000)))
+}
+
+func TestReadMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Metadata
+	}{
+		{
+			"version 1 headers rotated by 90°",
+			movie(1, videoTrak(1, rotate90, 1920, 1080, "avc1"), audioTrak()),
+			Metadata{DurationMs: 10010, Width: 1920, Height: 1080, VideoCodec: "avc1", AudioCodec: "mp4a", Rotation: 90},
+		},
+		{
+			"version 0 headers",
+			movie(0, audioTrak(), videoTrak(0, identityMatrix, 1280, 720, "hvc1")),
+			Metadata{DurationMs: 10010, Width: 1280, Height: 720, VideoCodec: "hvc1", AudioCodec: "mp4a"},
+		},
+		{
+			"rotated by 180°",
+			movie(0, videoTrak(0, rotate180, 1280, 720, "avc1")),
+			Metadata{DurationMs: 10010, Width: 1280, Height: 720, VideoCodec: "avc1", Rotation: 180},
+		},
+		{
+			"rotated by 270°",
+			movie(1, videoTrak(1, rotate270, 1280, 720, "avc1")),
+			Metadata{DurationMs: 10010, Width: 1280, Height: 720, VideoCodec: "avc1", Rotation: 270},
+		},
+		{
+			"dimensions from the sample entry",
+			movie(0, videoTrak(0, identityMatrix, 0, 0, "mp4v")),
+			Metadata{DurationMs: 10010, Width: 640, Height: 480, VideoCodec: "mp4v"},
+		},
+		{
+			"first video track wins",
+			movie(0, videoTrak(1, rotate90, 1920, 1080, "avc1"), videoTrak(0, identityMatrix, 320, 240, "mp4v")),
+			Metadata{DurationMs: 10010, Width: 1920, Height: 1080, VideoCodec: "avc1", Rotation: 90},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMetadata(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatalf("ReadMetadata() error = %v", err)
+			}
+
+			tt.want.Bitrate = int64(len(tt.data)) * 8 * 1000 / tt.want.DurationMs
+			tt.want.FrameRate = 300 * 30000 / 300300.0
+			if math.Abs(got.FrameRate-tt.want.FrameRate) > 1e-9 {
+				t.Errorf("FrameRate = %v, want %v", got.FrameRate, tt.want.FrameRate)
+			}
+			got.FrameRate = tt.want.FrameRate
+			if *got != tt.want {
+				t.Errorf("ReadMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMetadataRejectsBrokenFiles(t *testing.T) {
+	truncatedStts := stts([2]uint32{300, 1001})
+	binary.BigEndian.PutUint32(truncatedStts[12:16], 1000)
+	brokenTrak := trak(tkhd(0, identityMatrix, 1280, 720), mediaHeader("mdhd", 0, 30000, 300300), hdlr("vide"), truncatedStts)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no moov", concat(ftyp("isom"), isoBox("mdat", nil))},
+		{"stts entries past the box", movie(0, brokenTrak)},
+		{"truncated tkhd", movie(0, isoBox("trak", fullBox("tkhd", 1, make([]byte, 40))))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ReadMetadata(bytes.NewReader(tt.data), int64(len(tt.data))); !errors.Is(err, ErrUnknownContainer) {
+				t.Errorf("ReadMetadata() = %+v, %v; want ErrUnknownContainer", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	FormatMP4  = "mp4"
+	FormatMOV  = "mov"
+	FormatMKV  = "mkv"
+	FormatWebM = "webm"
+	FormatAVI  = "avi"
+)
+
+var ErrUnknownContainer = errors.New("unknown video container")
+
+type Container struct {
+	Format string
+	Brand  string
+}
+
+func Detect(r io.ReaderAt, size int64) (*Container, error) {
+	var magic [4]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		return nil, ErrUnknownContainer
+	}
+	if binary.BigEndian.Uint32(magic[:]) == idEBML {
+		return detectMatroska(r, size)
+	}
+	if string(magic[:]) == "RIFF" {
+		return detectAVI(r, size)
+	}
+	return detectISOBMFF(r, size)
+}
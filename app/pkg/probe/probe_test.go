@@ -0,0 +1,95 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func isoBox(boxType string, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(b, boxType...), payload...)
+}
+
+// isoLargeBox writes the size as the 64-bit largesize that follows a size field of 1.
+func isoLargeBox(boxType string, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, 1)
+	b = append(b, boxType...)
+	b = binary.BigEndian.AppendUint64(b, uint64(16+len(payload)))
+	return append(b, payload...)
+}
+
+func ftyp(brand string) []byte {
+	return isoBox("ftyp", []byte(brand+"\x00\x00\x02\x00isommp41"))
+}
+
+// ebmlElement encodes id followed by a one-byte size, so payloads must stay below 127 bytes.
+func ebmlElement(id []byte, payload []byte) []byte {
+	b := append(append([]byte{}, id...), 0x80|byte(len(payload)))
+	return append(b, payload...)
+}
+
+func ebmlFile(docType string) []byte {
+	header := ebmlElement([]byte{0x1A, 0x45, 0xDF, 0xA3}, ebmlElement([]byte{0x42, 0x82}, []byte(docType)))
+	// A size byte with all value bits set is the unknown size of a live-recorded segment.
+	segment := append([]byte{0x18, 0x53, 0x80, 0x67, 0xFF}, 0xEC, 0x81, 0x00)
+	return append(header, segment...)
+}
+
+func riff(form string, chunks []byte) []byte {
+	b := []byte("RIFF")
+	b = binary.LittleEndian.AppendUint32(b, uint32(4+len(chunks)))
+	return append(append(b, form...), chunks...)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestDetect(t *testing.T) {
+	moov := isoBox("moov", []byte("mvhd"))
+	tooLargeMoov := binary.BigEndian.AppendUint32(nil, 1000)
+	tooLargeMoov = append(tooLargeMoov, "moov"...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want *Container
+	}{
+		{"ftyp and moov", concat(ftyp("isom"), moov), &Container{Format: FormatMP4, Brand: "isom"}},
+		{"qt brand", concat(ftyp("qt  "), moov), &Container{Format: FormatMOV, Brand: "qt"}},
+		{"moov without ftyp", concat(isoBox("wide", nil), moov, isoBox("mdat", []byte{1, 2, 3})), &Container{Format: FormatMOV, Brand: "qt"}},
+		{"ftyp without moov", concat(ftyp("isom"), isoBox("mdat", nil)), nil},
+		{"box size 0 runs to end of file", concat(ftyp("mp42"), []byte{0, 0, 0, 0}, []byte("moov"), []byte("payload")), &Container{Format: FormatMP4, Brand: "mp42"}},
+		{"box size 1 uses 64-bit size", concat(ftyp("isom"), isoLargeBox("moov", []byte("mvhd"))), &Container{Format: FormatMP4, Brand: "isom"}},
+		{"truncated box header", concat(ftyp("isom"), moov, []byte{0, 0, 0}), nil},
+		{"box larger than file", concat(ftyp("isom"), tooLargeMoov), nil},
+		{"second ftyp", concat(ftyp("isom"), moov, ftyp("isom")), nil},
+		{"empty", nil, nil},
+		{"webm with unknown-size segment", ebmlFile("webm"), &Container{Format: FormatWebM, Brand: "webm"}},
+		{"matroska", ebmlFile("matroska"), &Container{Format: FormatMKV, Brand: "matroska"}},
+		{"unknown doc type", ebmlFile("mka3"), nil},
+		{"ebml without segment", ebmlElement([]byte{0x1A, 0x45, 0xDF, 0xA3}, ebmlElement([]byte{0x42, 0x82}, []byte("webm"))), nil},
+		{"avi", riff("AVI ", concat([]byte("LIST"), []byte{4, 0, 0, 0}, []byte("hdrl"))), &Container{Format: FormatAVI, Brand: "AVI"}},
+		{"riff that is not avi", riff("WAVE", concat([]byte("fmt "), []byte{16, 0, 0, 0}, make([]byte, 16))), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if tt.want == nil {
+				if !errors.Is(err, ErrUnknownContainer) {
+					t.Fatalf("Detect() = %+v, %v; want ErrUnknownContainer", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("Detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
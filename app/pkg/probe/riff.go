@@ -0,0 +1,25 @@
+package probe
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+func detectAVI(r io.ReaderAt, size int64) (*Container, error) {
+	var header [24]byte
+	if size < int64(len(header)) {
+		return nil, ErrUnknownContainer
+	}
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, ErrUnknownContainer
+	}
+
+	riffSize := int64(binary.LittleEndian.Uint32(header[4:8]))
+	if string(header[8:12]) != "AVI " || riffSize < 16 {
+		return nil, ErrUnknownContainer
+	}
+	if string(header[12:16]) != "LIST" || string(header[20:24]) != "hdrl" {
+		return nil, ErrUnknownContainer
+	}
+	return &Container{Format: FormatAVI, Brand: "AVI"}, nil
+}
@@ -0,0 +1,98 @@
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"io"
+	"mime/multipart"
+	"net/url"
+)
+
+const (
+	// maxJSONBodySize bounds JSON request bodies. The server streams request bodies for uploads, which
+	// lifts fasthttp's MaxRequestBodySize, so JSON handlers must never call ctx.PostBody() directly.
+	maxJSONBodySize       = 1024 * 1024
+	maxTranscriptBodySize = 8 * 1024 * 1024
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// readBody reads the request body, refusing bodies over limit before and while reading them.
+func readBody(ctx *fasthttp.RequestCtx, limit int64) ([]byte, error) {
+	if length := ctx.Request.Header.ContentLength(); length > 0 && int64(length) > limit {
+		return nil, errBodyTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(requestBodyReader(ctx), limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
+
+// readJSONBody reads at most limit bytes of the request body and decodes them into v.
+func readJSONBody(ctx *fasthttp.RequestCtx, v any, limit int64) error {
+	body, err := readBody(ctx, limit)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// formValue looks key up in the query string and then in a url-encoded or multipart body of bounded
+// size, in place of ctx.FormValue, which would buffer or spool the whole body.
+func formValue(ctx *fasthttp.RequestCtx, key string) (string, error) {
+	if ctx.QueryArgs().Has(key) {
+		return string(ctx.QueryArgs().Peek(key)), nil
+	}
+	contentType := ctx.Request.Header.ContentType()
+	multipartForm := bytes.HasPrefix(contentType, []byte("multipart/form-data"))
+	if !multipartForm && !bytes.HasPrefix(contentType, []byte("application/x-www-form-urlencoded")) {
+		return "", nil
+	}
+	body, err := readBody(ctx, maxJSONBodySize)
+	if err != nil {
+		return "", err
+	}
+
+	if multipartForm {
+		boundary := ctx.Request.Header.MultipartFormBoundary()
+		if len(boundary) == 0 {
+			return "", errors.New("missing multipart boundary")
+		}
+		form, err := multipart.NewReader(bytes.NewReader(body), string(boundary)).ReadForm(maxJSONBodySize)
+		if err != nil {
+			return "", err
+		}
+		defer form.RemoveAll()
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0], nil
+		}
+		return "", nil
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", err
+	}
+	return values.Get(key), nil
+}
+
+// decodeJSONBody is readJSONBody that answers 413 or 400 itself; it reports whether decoding succeeded.
+func decodeJSONBody(ctx *fasthttp.RequestCtx, v any, limit int64) bool {
+	err := readJSONBody(ctx, v, limit)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errBodyTooLarge):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusRequestEntityTooLarge, err, fmt.Sprintf("Request body exceeds %d bytes", limit))
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid request body")
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package route
+
+import (
+	"errors"
+	"flag"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/Dimoonevs/video-service/app/pkg/signedurl"
+	"github.com/valyala/fasthttp"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var trustProxyHeaders = flag.Bool("trustProxyHeaders", false, "take the client IP from X-Real-IP / X-Forwarded-For")
+
+func handleDelivery(ctx *fasthttp.RequestCtx, remainingPath string) {
+	if !ctx.IsGet() && !ctx.IsHead() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+	if !signedurl.Enabled() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	idStr, escapedRendition, ok := strings.Cut(strings.TrimPrefix(remainingPath, "/"), "/")
+	fileID, err := strconv.Atoi(idStr)
+	if !ok || err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+	rendition, err := url.PathUnescape(escapedRendition)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Endpoint not found")
+		return
+	}
+
+	args := ctx.QueryArgs()
+	params := signedurl.Params{
+		Expires:   int64(args.GetUintOrZero("exp")),
+		KeyID:     string(args.Peek("kid")),
+		BindIP:    string(args.Peek("ip")) == "1",
+		Signature: string(args.Peek("sig")),
+	}
+	if err = signedurl.Verify(fileID, rendition, params, clientIP(ctx)); err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusForbidden, err, "Access denied")
+		return
+	}
+
+	stream, err := service.GetDeliveryStream(fileID, rendition)
+	if err != nil {
+		if errors.Is(err, mysql.ErrVideoNotFound) || errors.Is(err, storage.ErrNotFound) {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+			return
+		}
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to open video")
+		return
+	}
+
+	serveObject(ctx, stream.ContentType, stream.Info, stream.Open)
+}
+
+func clientIP(ctx *fasthttp.RequestCtx) string {
+	if *trustProxyHeaders {
+		if ip := string(ctx.Request.Header.Peek("X-Real-IP")); ip != "" {
+			return ip
+		}
+		if forwarded := string(ctx.Request.Header.Peek("X-Forwarded-For")); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	return ctx.RemoteIP().String()
+}
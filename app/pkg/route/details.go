@@ -0,0 +1,50 @@
+package route
+
+import (
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+)
+
+const maxDetailsBodySize = 64 * 1024
+
+// handleVideoByID serves GET and PATCH on /video/{id}; PATCH edits title, description, tags, language
+// and custom_fields.
+func handleVideoByID(ctx *fasthttp.RequestCtx, videoID int) {
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	var video *models.InfoVideosResp
+	switch string(ctx.Method()) {
+	case fasthttp.MethodGet:
+		video, err = service.GetVideo(videoID, userID, clientIP(ctx))
+	case fasthttp.MethodPatch:
+		var req models.VideoDetailsReq
+		if !decodeJSONBody(ctx, &req, maxDetailsBodySize) {
+			return
+		}
+		video, err = service.UpdateVideoDetails(videoID, userID, &req, clientIP(ctx))
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidDetails):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Invalid video details")
+		case errors.Is(err, mysql.ErrVideoNotFound):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to get video")
+		}
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Video info retrieved successfully", video)
+}
@@ -0,0 +1,106 @@
+package route
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"time"
+)
+
+var (
+	ssePollInterval      = flag.Duration("ssePollInterval", time.Second, "how often the event stream checks for new status changes")
+	sseHeartbeatInterval = flag.Duration("sseHeartbeatInterval", 15*time.Second, "interval of keep-alive comments on idle event streams")
+	sseMaxDuration       = flag.Duration("sseMaxDuration", time.Hour, "lifetime of an event stream before the client has to reconnect")
+	sseRetry             = flag.Duration("sseRetry", 3*time.Second, "reconnect delay suggested to event stream clients")
+)
+
+// handleEvents streams the user's video events as Server-Sent Events. A reconnecting client resumes after
+// the Last-Event-ID header, or the last_event_id query parameter on a fresh connection. The stream needs
+// the Authorization header like every user route, so browsers read it with fetch rather than EventSource.
+func handleEvents(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	lastEventIDStr := string(ctx.Request.Header.Peek("Last-Event-ID"))
+	if lastEventIDStr == "" {
+		lastEventIDStr = string(ctx.QueryArgs().Peek("last_event_id"))
+	}
+	var lastEventID int64
+	if lastEventIDStr != "" {
+		if lastEventID, err = strconv.ParseInt(lastEventIDStr, 10, 64); err != nil || lastEventID < 0 {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid Last-Event-ID")
+			return
+		}
+	} else if lastEventID, err = service.LatestEventID(); err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to open event stream")
+		return
+	}
+
+	ip := clientIP(ctx)
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		streamEvents(w, userID, lastEventID, ip)
+	})
+}
+
+func streamEvents(w *bufio.Writer, userID int, lastEventID int64, clientIP string) {
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	deadline := time.Now().Add(*sseMaxDuration)
+	lastWrite := time.Now()
+	ticker := time.NewTicker(*ssePollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if time.Now().After(deadline) {
+			return
+		}
+
+		events, err := service.GetVideoEvents(userID, lastEventID, clientIP)
+		if err != nil {
+			logrus.Errorf("failed to read events of user %d: %v", userID, err)
+			continue
+		}
+		for _, event := range events {
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				logrus.Errorf("failed to marshal event %d: %v", event.Id, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Name, data)
+			lastEventID = event.Id
+		}
+
+		if len(events) == 0 {
+			if time.Since(lastWrite) < *sseHeartbeatInterval {
+				continue
+			}
+			w.WriteString(": ping\n\n")
+		}
+		// A failed flush means the client went away.
+		if err = w.Flush(); err != nil {
+			return
+		}
+		lastWrite = time.Now()
+	}
+}
@@ -0,0 +1,122 @@
+package route
+
+import (
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+// handleFolderRoutes serves /folders (list, create), /folders/{id} (get, rename or move with PATCH, delete
+// with ?policy=restrict|move_to_parent|delete_contents) and /folders/{id}/videos (POST adds, DELETE removes).
+func handleFolderRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	if remainingPath == "" || remainingPath == "/" {
+		switch {
+		case ctx.IsGet():
+			folders, err := service.GetFolders(userID)
+			if err != nil {
+				writeFolderError(ctx, err)
+				return
+			}
+			respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Folders retrieved successfully", folders)
+		case ctx.IsPost():
+			var req models.FolderReq
+			if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+				return
+			}
+			folder, err := service.CreateFolder(userID, &req)
+			if err != nil {
+				writeFolderError(ctx, err)
+				return
+			}
+			respJSON.WriteJSONResponse(ctx, fasthttp.StatusCreated, "Folder created successfully", folder)
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		}
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(remainingPath, "/"), "/")
+	folderID, err := strconv.Atoi(idStr)
+	if err != nil || folderID <= 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	method := string(ctx.Method())
+	switch {
+	case action == "" && ctx.IsGet():
+		folder, err := service.GetFolder(folderID, userID)
+		if err != nil {
+			writeFolderError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Folder retrieved successfully", folder)
+	case action == "" && method == fasthttp.MethodPatch:
+		var req models.FolderReq
+		if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+			return
+		}
+		folder, err := service.UpdateFolder(folderID, userID, &req)
+		if err != nil {
+			writeFolderError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Folder updated successfully", folder)
+	case action == "" && method == fasthttp.MethodDelete:
+		resp, err := service.DeleteFolder(folderID, userID, string(ctx.QueryArgs().Peek("policy")))
+		if err != nil {
+			writeFolderError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Folder deleted successfully", resp)
+	case action == "videos" && (ctx.IsPost() || method == fasthttp.MethodDelete):
+		var req models.FolderVideosReq
+		if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+			return
+		}
+		if ctx.IsPost() {
+			err = service.AddVideosToFolder(folderID, userID, &req)
+		} else {
+			err = service.RemoveVideosFromFolder(folderID, userID, &req)
+		}
+		if err != nil {
+			writeFolderError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Folder videos updated successfully", nil)
+	case action == "" || action == "videos":
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+	}
+}
+
+func writeFolderError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidFolder), errors.Is(err, mysql.ErrFolderTooDeep):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Invalid folder")
+	case errors.Is(err, service.ErrFolderLimit):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Folder limit reached")
+	case errors.Is(err, mysql.ErrFolderNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Folder not found")
+	case errors.Is(err, mysql.ErrVideoNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+	case errors.Is(err, mysql.ErrFolderExists), errors.Is(err, mysql.ErrFolderNotEmpty), errors.Is(err, mysql.ErrFolderCycle):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusConflict, err, "Folder conflict")
+	case errors.Is(err, mysql.ErrIllegalTransition):
+		writeTransitionError(ctx, err)
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Folder operation failed")
+	}
+}
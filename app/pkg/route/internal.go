@@ -0,0 +1,127 @@
+package route
+
+import (
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+const internalBasePath = "/video-service/internal"
+
+var internalAPITokens = flag.String("internalAPITokens", "", "comma separated bearer tokens accepted by the internal API, empty disables it")
+
+// handleInternalRoutes serves service-to-service endpoints such as the converter callbacks.
+// They are authenticated with a shared bearer token instead of a user JWT.
+func handleInternalRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	if *internalAPITokens == "" {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+	if !internalTokenValid(string(ctx.Request.Header.Peek("Authorization"))) {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, nil, "Invalid internal token")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(remainingPath, "/jobs/"):
+		handleJobRoutes(ctx, remainingPath[len("/jobs"):])
+	case strings.HasPrefix(remainingPath, "/video/"):
+		handleInternalVideoRoutes(ctx, remainingPath[len("/video/"):])
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+	}
+}
+
+func handleInternalVideoRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	idStr, action, _ := strings.Cut(remainingPath, "/")
+	videoID, err := strconv.Atoi(idStr)
+	if err != nil || videoID <= 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	switch action {
+	case "renditions":
+		handleRegisterRenditions(ctx, videoID)
+	case "transcript":
+		handleSetTranscript(ctx, videoID)
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+	}
+}
+
+func handleRegisterRenditions(ctx *fasthttp.RequestCtx, videoID int) {
+	if !ctx.IsPost() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	var req models.RegisterRenditionsReq
+	if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+		return
+	}
+
+	if err := service.RegisterRenditions(videoID, &req); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRendition):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Invalid renditions")
+		case errors.Is(err, mysql.ErrVideoNotFound):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+		case errors.Is(err, mysql.ErrIllegalTransition):
+			writeTransitionError(ctx, err)
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to register renditions")
+		}
+		return
+	}
+
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Renditions registered successfully", nil)
+}
+
+func handleSetTranscript(ctx *fasthttp.RequestCtx, videoID int) {
+	if !ctx.IsPut() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	var req models.TranscriptReq
+	if !decodeJSONBody(ctx, &req, maxTranscriptBodySize) {
+		return
+	}
+
+	if err := service.SetTranscript(videoID, &req); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTranscript):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Invalid transcript")
+		case errors.Is(err, mysql.ErrVideoNotFound):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to store transcript")
+		}
+		return
+	}
+
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Transcript stored successfully", nil)
+}
+
+func internalTokenValid(header string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	valid := false
+	for _, candidate := range strings.Split(*internalAPITokens, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate != "" && subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,112 @@
+package route
+
+import (
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+func handleJobRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	if !ctx.IsPost() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+	if remainingPath == "/claim" {
+		handleJobClaim(ctx)
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(remainingPath, "/"), "/")
+	jobID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || jobID <= 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	switch action {
+	case "heartbeat":
+		handleJobHeartbeat(ctx, jobID)
+	case "complete":
+		handleJobComplete(ctx, jobID)
+	case "fail":
+		handleJobFail(ctx, jobID)
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+	}
+}
+
+func handleJobClaim(ctx *fasthttp.RequestCtx) {
+	var req models.JobClaimReq
+	if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+		return
+	}
+
+	job, err := service.ClaimJob(&req)
+	if err != nil {
+		writeJobError(ctx, err)
+		return
+	}
+	if job == nil {
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Job claimed successfully", job)
+}
+
+func handleJobHeartbeat(ctx *fasthttp.RequestCtx, jobID int64) {
+	var req models.JobLeaseReq
+	if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+		return
+	}
+
+	job, err := service.HeartbeatJob(jobID, &req)
+	if err != nil {
+		writeJobError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Job lease extended successfully", job)
+}
+
+func handleJobComplete(ctx *fasthttp.RequestCtx, jobID int64) {
+	var req models.JobLeaseReq
+	if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+		return
+	}
+
+	if err := service.CompleteJob(jobID, &req); err != nil {
+		writeJobError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Job completed successfully", nil)
+}
+
+func handleJobFail(ctx *fasthttp.RequestCtx, jobID int64) {
+	var req models.JobFailReq
+	if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+		return
+	}
+
+	if err := service.FailJob(jobID, &req); err != nil {
+		writeJobError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Job failure recorded successfully", nil)
+}
+
+func writeJobError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidJobRequest):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid job request")
+	case errors.Is(err, mysql.ErrJobNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Job not found")
+	case errors.Is(err, mysql.ErrLeaseLost):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusConflict, err, "Job is not leased with this token")
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to process job")
+	}
+}
@@ -0,0 +1,94 @@
+package route
+
+import (
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseVideoListReq reads the listing query: limit, cursor, sort (created, updated, name, size, duration),
+// order (asc or desc; name defaults to asc, everything else to desc), status (comma separated or repeated),
+// is_stream, created_from and created_to (RFC 3339 or YYYY-MM-DD, the upper bound exclusive), name_prefix, id,
+// folder_id (a folder id or root for videos in no folder) and recursive to include subfolders.
+func parseVideoListReq(args *fasthttp.Args) (*models.VideoListReq, error) {
+	req := &models.VideoListReq{
+		Sort:   string(args.Peek("sort")),
+		Cursor: string(args.Peek("cursor")),
+	}
+	req.NamePrefix = string(args.Peek("name_prefix"))
+
+	var err error
+	if args.Has("limit") {
+		if req.Limit, err = strconv.Atoi(string(args.Peek("limit"))); err != nil || req.Limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer")
+		}
+	}
+	if args.Has("id") {
+		if req.VideoId, err = strconv.Atoi(string(args.Peek("id"))); err != nil || req.VideoId <= 0 {
+			return nil, fmt.Errorf("id must be a positive integer")
+		}
+	}
+
+	if folder := string(args.Peek("folder_id")); folder == "root" {
+		req.Unfiled = true
+	} else if folder != "" {
+		if req.FolderId, err = strconv.Atoi(folder); err != nil || req.FolderId <= 0 {
+			return nil, fmt.Errorf("folder_id must be a positive integer or root")
+		}
+	}
+	if args.Has("recursive") {
+		if req.Recursive, err = strconv.ParseBool(string(args.Peek("recursive"))); err != nil {
+			return nil, fmt.Errorf("recursive must be true or false")
+		}
+	}
+
+	switch order := string(args.Peek("order")); order {
+	case "":
+		req.Desc = req.Sort != models.SortName
+	case "asc", "desc":
+		req.Desc = order == "desc"
+	default:
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+
+	for _, value := range args.PeekMulti("status") {
+		for _, status := range strings.Split(string(value), ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				req.Statuses = append(req.Statuses, status)
+			}
+		}
+	}
+
+	if args.Has("is_stream") {
+		isStream, err := strconv.ParseBool(string(args.Peek("is_stream")))
+		if err != nil {
+			return nil, fmt.Errorf("is_stream must be true or false")
+		}
+		req.IsStream = &isStream
+	}
+
+	if req.CreatedFrom, err = parseListTime(args, "created_from"); err != nil {
+		return nil, err
+	}
+	if req.CreatedTo, err = parseListTime(args, "created_to"); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func parseListTime(args *fasthttp.Args, name string) (time.Time, error) {
+	value := string(args.Peek(name))
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp or a date", name)
+}
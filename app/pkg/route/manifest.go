@@ -0,0 +1,69 @@
+package route
+
+import (
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"net/url"
+)
+
+const (
+	hlsContentType  = "application/vnd.apple.mpegurl"
+	dashContentType = "application/dash+xml"
+)
+
+// handleHLSPlaylist serves the master playlist when rendition is empty and the rendition's media playlist otherwise.
+func handleHLSPlaylist(ctx *fasthttp.RequestCtx, videoID int, rendition string) {
+	if !ctx.IsGet() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	var playlist string
+	if rendition == "" {
+		playlist, err = service.GetHLSMaster(videoID, userID)
+	} else if rendition, err = url.PathUnescape(rendition); err == nil {
+		playlist, err = service.GetHLSMedia(videoID, userID, rendition, clientIP(ctx))
+	}
+	writeManifest(ctx, hlsContentType, playlist, err)
+}
+
+func handleDASHManifest(ctx *fasthttp.RequestCtx, videoID int) {
+	if !ctx.IsGet() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	mpd, err := service.GetDASHManifest(videoID, userID, clientIP(ctx))
+	writeManifest(ctx, dashContentType, mpd, err)
+}
+
+func writeManifest(ctx *fasthttp.RequestCtx, contentType, body string, err error) {
+	if err != nil {
+		if errors.Is(err, mysql.ErrVideoNotFound) || errors.Is(err, service.ErrRenditionNotFound) {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Manifest not found")
+			return
+		}
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to build manifest")
+		return
+	}
+
+	ctx.Response.Header.Set("Cache-Control", "private, no-cache")
+	ctx.SetContentType(contentType)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetBodyString(body)
+}
@@ -0,0 +1,156 @@
+package route
+
+import (
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+const publicPlaylistsPath = "/video-service/public/playlists/"
+
+// handlePlaylistRoutes serves /playlists (list, create), /playlists/{id} (get, PATCH, delete),
+// /playlists/{id}/items (POST adds videos), /playlists/{id}/items/{item} (DELETE removes one),
+// /playlists/{id}/items/order (PUT reorders) and /playlists/{id}/feed.
+func handlePlaylistRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	if remainingPath == "" || remainingPath == "/" {
+		switch {
+		case ctx.IsGet():
+			playlists, err := service.GetPlaylists(userID)
+			if err != nil {
+				writePlaylistError(ctx, err)
+				return
+			}
+			respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Playlists retrieved successfully", playlists)
+		case ctx.IsPost():
+			var req models.PlaylistReq
+			if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+				return
+			}
+			playlist, err := service.CreatePlaylist(userID, &req)
+			if err != nil {
+				writePlaylistError(ctx, err)
+				return
+			}
+			respJSON.WriteJSONResponse(ctx, fasthttp.StatusCreated, "Playlist created successfully", playlist)
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		}
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(remainingPath, "/"), "/")
+	playlistID, err := strconv.Atoi(idStr)
+	if err != nil || playlistID <= 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	var playlist *models.Playlist
+	method := string(ctx.Method())
+	switch {
+	case action == "" && ctx.IsGet():
+		playlist, err = service.GetPlaylist(playlistID, userID)
+	case action == "" && method == fasthttp.MethodPatch:
+		var req models.PlaylistReq
+		if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+			return
+		}
+		playlist, err = service.UpdatePlaylist(playlistID, userID, &req)
+	case action == "" && method == fasthttp.MethodDelete:
+		if err = service.DeletePlaylist(playlistID, userID); err != nil {
+			writePlaylistError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Playlist deleted successfully", nil)
+		return
+	case action == "items" && ctx.IsPost():
+		var req models.PlaylistItemsReq
+		if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+			return
+		}
+		playlist, err = service.AddPlaylistItems(playlistID, userID, &req)
+	case action == "items/order" && ctx.IsPut():
+		var req models.PlaylistOrderReq
+		if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+			return
+		}
+		playlist, err = service.ReorderPlaylistItems(playlistID, userID, &req)
+	case strings.HasPrefix(action, "items/") && action != "items/order" && method == fasthttp.MethodDelete:
+		itemID, parseErr := strconv.ParseInt(strings.TrimPrefix(action, "items/"), 10, 64)
+		if parseErr != nil || itemID <= 0 {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+			return
+		}
+		playlist, err = service.RemovePlaylistItem(playlistID, userID, itemID)
+	case action == "feed" && ctx.IsGet():
+		feed, err := service.GetPlaylistFeed(playlistID, userID, clientIP(ctx))
+		if err != nil {
+			writePlaylistError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Playlist feed retrieved successfully", feed)
+		return
+	case action == "" || action == "items" || strings.HasPrefix(action, "items/") || action == "feed":
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	if err != nil {
+		writePlaylistError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Playlist retrieved successfully", playlist)
+}
+
+// handleSharedPlaylist serves the feed of unlisted and public playlists without a user token.
+func handleSharedPlaylist(ctx *fasthttp.RequestCtx, key string) {
+	if !ctx.IsGet() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+	if key == "" || strings.Contains(key, "/") {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+	feed, err := service.GetSharedPlaylistFeed(key, clientIP(ctx))
+	if err != nil {
+		writePlaylistError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Playlist feed retrieved successfully", feed)
+}
+
+func writePlaylistError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPlaylist):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Invalid playlist")
+	case errors.Is(err, service.ErrPlaylistLimit), errors.Is(err, mysql.ErrPlaylistFull):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Playlist limit reached")
+	case errors.Is(err, mysql.ErrPlaylistOrder):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Invalid order")
+	case errors.Is(err, mysql.ErrPlaylistNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Playlist not found")
+	case errors.Is(err, mysql.ErrPlaylistItemNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Playlist item not found")
+	case errors.Is(err, mysql.ErrVideoNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+	case errors.Is(err, mysql.ErrPlaylistItemExists):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusConflict, err, "Video already in playlist")
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Playlist operation failed")
+	}
+}
@@ -1,30 +1,62 @@
 package route
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/Dimoonevs/user-service/app/pkg/jwt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
 	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
 	"github.com/Dimoonevs/video-service/app/internal/service"
 	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/Dimoonevs/video-service/app/pkg/signedurl"
 	"github.com/valyala/fasthttp"
 	"log"
 	"strconv"
 	"strings"
 )
 
+// StartBackgroundTasks launches the workers that run alongside the HTTP server.
+func StartBackgroundTasks() {
+	service.StartJobReaper()
+	service.StartWebhookDispatcher()
+	service.StartOutboxRelay()
+	service.StartUploadSweeper()
+}
+
 func RequestHandler(ctx *fasthttp.RequestCtx) {
+	path := string(ctx.URI().Path())
+
 	if string(ctx.Method()) == fasthttp.MethodOptions {
+		if strings.HasPrefix(path, tusBasePath) {
+			setTusHeaders(ctx)
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+			return
+		}
 		ctx.SetStatusCode(fasthttp.StatusOK)
 		return
 	}
 
-	path := string(ctx.URI().Path())
-
 	if !strings.HasPrefix(path, "/video-service") {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
 		return
 	}
 
+	if strings.HasPrefix(path, signedurl.DeliveryPath+"/") {
+		handleDelivery(ctx, path[len(signedurl.DeliveryPath):])
+		return
+	}
+
+	if strings.HasPrefix(path, publicPlaylistsPath) {
+		handleSharedPlaylist(ctx, path[len(publicPlaylistsPath):])
+		return
+	}
+
+	if strings.HasPrefix(path, internalBasePath+"/") {
+		handleInternalRoutes(ctx, path[len(internalBasePath):])
+		return
+	}
+
 	jwt.JWTMiddleware(func(ctx *fasthttp.RequestCtx) {
 		handleRoutes(ctx, path)
 	})(ctx)
@@ -36,12 +68,22 @@ func handleRoutes(ctx *fasthttp.RequestCtx, path string) {
 	switch {
 	case strings.HasPrefix(remainingPath, "/check"):
 		handleCheck(ctx)
+	case strings.HasPrefix(remainingPath, "/upload/tus"):
+		handleTusRoutes(ctx, remainingPath[len("/upload/tus"):])
 	case strings.HasPrefix(remainingPath, "/upload"):
 		if string(ctx.Method()) == "POST" {
 			handleUpload(ctx)
 		} else {
 			respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
 		}
+	case remainingPath == "/events":
+		handleEvents(ctx)
+	case strings.HasPrefix(remainingPath, "/folders"):
+		handleFolderRoutes(ctx, remainingPath[len("/folders"):])
+	case strings.HasPrefix(remainingPath, "/playlists"):
+		handlePlaylistRoutes(ctx, remainingPath[len("/playlists"):])
+	case strings.HasPrefix(remainingPath, "/webhooks"):
+		handleWebhookRoutes(ctx, remainingPath[len("/webhooks"):])
 	case strings.HasPrefix(remainingPath, "/video"):
 		handleVideoRoutes(ctx, remainingPath[len("/video"):])
 	default:
@@ -57,21 +99,113 @@ func handleVideoRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
 		handleVideoErrorsUpdate(ctx)
 	case "/links":
 		handlerVideoGetLinks(ctx)
+	case "/search":
+		handleVideoSearch(ctx)
 	case "":
 		handleVideoGetInfo(ctx)
+	default:
+		handleVideoByIDRoutes(ctx, remainingPath)
+	}
+}
+
+func handleVideoByIDRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(remainingPath, "/"), "/")
+	videoID, err := strconv.Atoi(idStr)
+	if err != nil || videoID <= 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	switch {
+	case action == "":
+		handleVideoByID(ctx, videoID)
+	case action == "stream":
+		handleVideoStream(ctx, videoID)
+	case action == "master.m3u8":
+		handleHLSPlaylist(ctx, videoID, "")
+	case action == "retry":
+		handleVideoRetry(ctx, videoID)
+	case action == "history":
+		handleVideoHistory(ctx, videoID)
+	case action == "manifest.mpd":
+		handleDASHManifest(ctx, videoID)
+	case strings.HasSuffix(action, "/index.m3u8"):
+		handleHLSPlaylist(ctx, videoID, strings.TrimSuffix(action, "/index.m3u8"))
 	default:
 		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
 	}
 }
 
+func handleVideoRetry(ctx *fasthttp.RequestCtx, videoID int) {
+	if !ctx.IsPost() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	retry, err := service.RetryFile(videoID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, mysql.ErrVideoNotFound):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+		case errors.Is(err, mysql.ErrIllegalTransition):
+			writeTransitionError(ctx, err)
+		case errors.Is(err, mysql.ErrRetryLimit):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, err, "Video cannot be retried")
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to retry video")
+		}
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusAccepted, "Video queued for conversion", retry)
+}
+
+func handleVideoHistory(ctx *fasthttp.RequestCtx, videoID int) {
+	if !ctx.IsGet() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	history, err := service.GetStatusHistory(videoID, userID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrVideoNotFound) {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+			return
+		}
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to get status history")
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Status history retrieved successfully", history)
+}
+
 func handleUpload(ctx *fasthttp.RequestCtx) {
-	isStreamParam := string(ctx.FormValue("is_stream"))
-	var isStream bool
-	if isStreamParam == "1" || isStreamParam == "true" {
-		isStream = true
-	} else if isStreamParam == "0" || isStreamParam == "false" {
-		isStream = false
-	} else {
+	// Only multipart bodies are streamed to disk; ctx.FormValue would buffer any other body whole.
+	if !bytes.HasPrefix(ctx.Request.Header.ContentType(), []byte("multipart/form-data")) {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, nil, "Expecting a multipart/form-data body")
+		return
+	}
+	// The form is spooled to disk as it is parsed, so its size is checked up front.
+	switch length := int64(ctx.Request.Header.ContentLength()); {
+	case length < 0:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusLengthRequired, nil, "Content-Length is required")
+		return
+	case length > service.MaxUploadSize():
+		respJSON.WriteJSONError(ctx, fasthttp.StatusRequestEntityTooLarge, service.ErrUploadTooLarge, "Request body too large")
+		return
+	}
+	isStream, err := parseIsStream(string(ctx.FormValue("is_stream")))
+	if err != nil {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, nil, "Invalid value for is_stream. Expecting true/false or 1/0")
 		return
 	}
@@ -94,9 +228,15 @@ func handleUpload(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	service.SaveFile(files, isStream, userID)
+	results := service.SaveFile(files, isStream, userID)
+	for _, result := range results {
+		if result.Status == models.UploadAccepted {
+			respJSON.WriteJSONResponse(ctx, fasthttp.StatusCreated, "File uploaded in process", results)
+			return
+		}
+	}
 
-	respJSON.WriteJSONResponse(ctx, fasthttp.StatusCreated, "File uploaded in process", nil)
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusUnprocessableEntity, "No file was accepted", results)
 }
 
 func handleVideoErrorsUpdate(ctx *fasthttp.RequestCtx) {
@@ -105,32 +245,47 @@ func handleVideoErrorsUpdate(ctx *fasthttp.RequestCtx) {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
 		return
 	}
-	if err := mysql.GetConnection().SetStatusIntoConv(userID); err != nil {
+	retried, err := service.RetryFailedStreams(userID)
+	if err != nil {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to update status error")
 		return
 	}
 
-	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Video errors updated successfully", nil)
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Video errors updated successfully", retried)
 }
 
 func handleVideoGetInfo(ctx *fasthttp.RequestCtx) {
-	videoStatus := string(ctx.FormValue("status"))
-	videoID := ctx.QueryArgs().GetUintOrZero("id")
 	userID, err := getUserIDFromContext(ctx)
 	if err != nil {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
 		return
 	}
-	resp, err := mysql.GetConnection().GetInfoVideos(videoStatus, userID, videoID)
+	req, err := parseVideoListReq(ctx.QueryArgs())
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid query")
+		return
+	}
+	resp, err := service.ListVideos(userID, req, clientIP(ctx))
 	if err != nil {
-		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to get video info")
+		switch {
+		case errors.Is(err, service.ErrInvalidListRequest):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid query")
+		case errors.Is(err, mysql.ErrFolderNotFound):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Folder not found")
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to get video info")
+		}
 		return
 	}
 	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Video info retrieved successfully", resp)
 }
 
 func handleDeleteVideoById(ctx *fasthttp.RequestCtx) {
-	idVideoStr := string(ctx.FormValue("id"))
+	idVideoStr, err := formValue(ctx, "id")
+	if errors.Is(err, errBodyTooLarge) {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusRequestEntityTooLarge, err, "Request body too large")
+		return
+	}
 	idVideo, err := strconv.Atoi(idVideoStr)
 	if err != nil {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid video ID")
@@ -143,7 +298,14 @@ func handleDeleteVideoById(ctx *fasthttp.RequestCtx) {
 		return
 	}
 	if err = service.DeleteVideo(idVideo, userID); err != nil {
-		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Error deleting video")
+		switch {
+		case errors.Is(err, mysql.ErrVideoNotFound):
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+		case errors.Is(err, mysql.ErrIllegalTransition):
+			writeTransitionError(ctx, err)
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Error deleting video")
+		}
 		return
 	}
 	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Video deleted successfully", nil)
@@ -155,7 +317,7 @@ func handlerVideoGetLinks(ctx *fasthttp.RequestCtx) {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
 		return
 	}
-	videoFormatLinksResp, err := mysql.GetConnection().GetVideoLinks(userID)
+	videoFormatLinksResp, err := service.GetVideoLinks(userID, clientIP(ctx))
 	if err != nil {
 		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to get video links")
 		return
@@ -164,10 +326,33 @@ func handlerVideoGetLinks(ctx *fasthttp.RequestCtx) {
 	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Video links retrieved successfully", videoFormatLinksResp)
 }
 
+// writeTransitionError answers a refused status change with 409 and the status the file is actually in.
+func writeTransitionError(ctx *fasthttp.RequestCtx, err error) {
+	var transitionErr *mysql.TransitionError
+	if !errors.As(err, &transitionErr) {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusConflict, err, "Status change not allowed")
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusConflict, transitionErr.Error(), map[string]models.FileStatus{
+		"current_status": transitionErr.Current,
+	})
+}
+
 func handleCheck(ctx *fasthttp.RequestCtx) {
 	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Service is running", nil)
 }
 
+func parseIsStream(isStreamParam string) (bool, error) {
+	switch isStreamParam {
+	case "1", "true":
+		return true, nil
+	case "0", "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid is_stream value: %s", isStreamParam)
+	}
+}
+
 func getUserIDFromContext(ctx *fasthttp.RequestCtx) (int, error) {
 	userIDValue := ctx.UserValue("userID")
 	userIDFloat, ok := userIDValue.(float64)
@@ -0,0 +1,48 @@
+package route
+
+import (
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+)
+
+// handleVideoSearch serves GET /video/search?q=...&limit=&offset= over the caller's videos.
+func handleVideoSearch(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	args := ctx.QueryArgs()
+	limit, offset := 0, 0
+	if args.Has("limit") {
+		if limit, err = args.GetUint("limit"); err != nil || limit == 0 {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "limit must be a positive integer")
+			return
+		}
+	}
+	if args.Has("offset") {
+		if offset, err = args.GetUint("offset"); err != nil {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "offset must be a non-negative integer")
+			return
+		}
+	}
+
+	resp, err := service.SearchVideos(userID, string(args.Peek("q")), limit, offset)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSearch) {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid search")
+			return
+		}
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to search videos")
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Search completed successfully", resp)
+}
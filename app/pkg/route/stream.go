@@ -0,0 +1,174 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidRange     = errors.New("invalid range")
+	errUnsupportedRange = errors.New("unsupported range")
+)
+
+func handleVideoStream(ctx *fasthttp.RequestCtx, videoID int) {
+	if !ctx.IsGet() && !ctx.IsHead() {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	stream, err := service.GetVideoStream(videoID, userID)
+	if err != nil {
+		if errors.Is(err, mysql.ErrVideoNotFound) || errors.Is(err, storage.ErrNotFound) {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Video not found")
+			return
+		}
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to open video")
+		return
+	}
+
+	serveObject(ctx, stream.ContentType, stream.Info, stream.Open)
+}
+
+// serveObject answers conditional and range requests for a stored object and streams the selected bytes.
+func serveObject(ctx *fasthttp.RequestCtx, contentType string, info *storage.ObjectInfo, open func(offset, length int64) (io.ReadCloser, error)) {
+	lastModified := info.ModTime.UTC().Truncate(time.Second)
+
+	ctx.Response.Header.Set("Accept-Ranges", "bytes")
+	ctx.Response.Header.Set("Cache-Control", "private")
+	if info.ETag != "" {
+		ctx.Response.Header.Set("ETag", info.ETag)
+	}
+	if !lastModified.IsZero() {
+		ctx.Response.Header.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if isNotModified(ctx, info.ETag, lastModified) {
+		ctx.SetStatusCode(fasthttp.StatusNotModified)
+		return
+	}
+
+	start, length := int64(0), info.Size
+	status := fasthttp.StatusOK
+	rangeHeader := string(ctx.Request.Header.Peek("Range"))
+	if rangeHeader != "" && ifRangeMatches(ctx, info.ETag, lastModified) {
+		rangeStart, rangeLength, err := parseRange(rangeHeader, info.Size)
+		switch {
+		case errors.Is(err, errInvalidRange):
+			ctx.Response.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+			respJSON.WriteJSONError(ctx, fasthttp.StatusRequestedRangeNotSatisfiable, err, "Range not satisfiable")
+			return
+		case err == nil:
+			start, length = rangeStart, rangeLength
+			status = fasthttp.StatusPartialContent
+			ctx.Response.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, info.Size))
+		}
+	}
+
+	ctx.SetContentType(contentType)
+	ctx.SetStatusCode(status)
+	if ctx.IsHead() {
+		ctx.Response.Header.SetContentLength(int(length))
+		return
+	}
+
+	// Reading to the end lets the local backend hand over the *os.File, which fasthttp sends with sendfile.
+	readLength := length
+	if start+length == info.Size {
+		readLength = -1
+	}
+	body, err := open(start, readLength)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to read video")
+		return
+	}
+	ctx.SetBodyStream(body, int(length))
+}
+
+func isNotModified(ctx *fasthttp.RequestCtx, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := string(ctx.Request.Header.Peek("If-None-Match")); ifNoneMatch != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := string(ctx.Request.Header.Peek("If-Modified-Since")); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !lastModified.After(since)
+	}
+	return false
+}
+
+func ifRangeMatches(ctx *fasthttp.RequestCtx, etag string, lastModified time.Time) bool {
+	ifRange := string(ctx.Request.Header.Peek("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, "\"") {
+		return etag != "" && ifRange == etag
+	}
+	since, err := http.ParseTime(ifRange)
+	return err == nil && !lastModified.IsZero() && lastModified.Equal(since)
+}
+
+// parseRange supports a single byte range; anything else is served as a full response.
+func parseRange(header string, size int64) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, errUnsupportedRange
+	}
+
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, errInvalidRange
+	}
+
+	if startStr == "" {
+		suffix, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffix <= 0 || size == 0 {
+			return 0, 0, errInvalidRange
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, suffix, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, errInvalidRange
+	}
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end - start + 1, nil
+}
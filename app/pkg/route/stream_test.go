@@ -0,0 +1,149 @@
+package route
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dimoonevs/video-service/app/internal/repo/storage"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		size       int64
+		wantStart  int64
+		wantLength int64
+		wantErr    error
+	}{
+		{"closed range", "bytes=0-499", 1000, 0, 500, nil},
+		{"single byte", "bytes=999-999", 1000, 999, 1, nil},
+		{"open-ended", "bytes=500-", 1000, 500, 500, nil},
+		{"end clamped to size", "bytes=900-1999", 1000, 900, 100, nil},
+		{"suffix", "bytes=-200", 1000, 800, 200, nil},
+		{"suffix longer than the object", "bytes=-2000", 1000, 0, 1000, nil},
+		{"surrounding spaces", "bytes= 10-19 ", 1000, 10, 10, nil},
+		{"start at size", "bytes=1000-", 1000, 0, 0, errInvalidRange},
+		{"start past size", "bytes=1000-1100", 1000, 0, 0, errInvalidRange},
+		{"end before start", "bytes=500-400", 1000, 0, 0, errInvalidRange},
+		{"empty suffix", "bytes=-0", 1000, 0, 0, errInvalidRange},
+		{"suffix of an empty object", "bytes=-10", 0, 0, 0, errInvalidRange},
+		{"range of an empty object", "bytes=0-", 0, 0, 0, errInvalidRange},
+		{"no bounds", "bytes=-", 1000, 0, 0, errInvalidRange},
+		{"no dash", "bytes=5", 1000, 0, 0, errInvalidRange},
+		{"not a number", "bytes=a-9", 1000, 0, 0, errInvalidRange},
+		{"negative start", "bytes=-5-9", 1000, 0, 0, errInvalidRange},
+		{"multi-range", "bytes=0-99,200-299", 1000, 0, 0, errUnsupportedRange},
+		{"other unit", "items=0-99", 1000, 0, 0, errUnsupportedRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, length, err := parseRange(tt.header, tt.size)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseRange(%q, %d) error = %v, want %v", tt.header, tt.size, err, tt.wantErr)
+			}
+			if start != tt.wantStart || length != tt.wantLength {
+				t.Errorf("parseRange(%q, %d) = %d, %d; want %d, %d", tt.header, tt.size, start, length, tt.wantStart, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		ifRange      string
+		etag         string
+		lastModified time.Time
+		want         bool
+	}{
+		{"no If-Range", "", `"abc"`, modified, true},
+		{"same strong etag", `"abc"`, `"abc"`, modified, true},
+		{"other etag", `"xyz"`, `"abc"`, modified, false},
+		{"no etag stored", `"abc"`, "", modified, false},
+		{"weak etag sent", `W/"abc"`, `"abc"`, modified, false},
+		{"weak etag stored", `"abc"`, `W/"abc"`, modified, false},
+		{"same weak etag", `W/"abc"`, `W/"abc"`, modified, false},
+		{"same date", modified.Format(http.TimeFormat), `"abc"`, modified, true},
+		{"older date", modified.Add(-time.Hour).Format(http.TimeFormat), `"abc"`, modified, false},
+		{"newer date", modified.Add(time.Hour).Format(http.TimeFormat), `"abc"`, modified, false},
+		{"date without a stored modification time", modified.Format(http.TimeFormat), `"abc"`, time.Time{}, false},
+		{"malformed date", "yesterday", `"abc"`, modified, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			if tt.ifRange != "" {
+				ctx.Request.Header.Set("If-Range", tt.ifRange)
+			}
+			if got := ifRangeMatches(&ctx, tt.etag, tt.lastModified); got != tt.want {
+				t.Errorf("ifRangeMatches(If-Range: %q, etag %q) = %v, want %v", tt.ifRange, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeObjectRange(t *testing.T) {
+	const content = "0123456789"
+	info := &storage.ObjectInfo{Size: int64(len(content)), ModTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), ETag: `"abc"`}
+	open := func(offset, length int64) (io.ReadCloser, error) {
+		if length < 0 {
+			length = int64(len(content)) - offset
+		}
+		return io.NopCloser(strings.NewReader(content[offset : offset+length])), nil
+	}
+
+	tests := []struct {
+		name             string
+		rangeHeader      string
+		ifRange          string
+		wantStatus       int
+		wantContentRange string
+		wantBody         string
+	}{
+		{"no range", "", "", fasthttp.StatusOK, "", content},
+		{"closed range", "bytes=2-4", "", fasthttp.StatusPartialContent, "bytes 2-4/10", "234"},
+		{"suffix", "bytes=-3", "", fasthttp.StatusPartialContent, "bytes 7-9/10", "789"},
+		{"open-ended", "bytes=6-", "", fasthttp.StatusPartialContent, "bytes 6-9/10", "6789"},
+		{"out of bounds", "bytes=10-", "", fasthttp.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+		{"multi-range is served in full", "bytes=0-1,4-5", "", fasthttp.StatusOK, "", content},
+		{"matching If-Range", "bytes=2-4", `"abc"`, fasthttp.StatusPartialContent, "bytes 2-4/10", "234"},
+		{"stale If-Range is served in full", "bytes=2-4", `"old"`, fasthttp.StatusOK, "", content},
+		{"weak If-Range is served in full", "bytes=2-4", `W/"abc"`, fasthttp.StatusOK, "", content},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			if tt.rangeHeader != "" {
+				ctx.Request.Header.Set("Range", tt.rangeHeader)
+			}
+			if tt.ifRange != "" {
+				ctx.Request.Header.Set("If-Range", tt.ifRange)
+			}
+
+			serveObject(&ctx, "video/mp4", info, open)
+
+			if got := ctx.Response.StatusCode(); got != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", got, tt.wantStatus)
+			}
+			if got := string(ctx.Response.Header.Peek("Content-Range")); got != tt.wantContentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantContentRange)
+			}
+			if tt.wantBody != "" {
+				if got := string(ctx.Response.Body()); got != tt.wantBody {
+					t.Errorf("body = %q, want %q", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package route
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,creation-with-upload,termination,expiration"
+	tusBasePath   = "/video-service/upload/tus"
+)
+
+func setTusHeaders(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set("Tus-Resumable", tusVersion)
+	ctx.Response.Header.Set("Tus-Version", tusVersion)
+	ctx.Response.Header.Set("Tus-Extension", tusExtensions)
+	ctx.Response.Header.Set("Tus-Max-Size", strconv.FormatInt(service.MaxUploadSize(), 10))
+}
+
+func handleTusRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	ctx.Response.Header.Set("Tus-Resumable", tusVersion)
+
+	if string(ctx.Request.Header.Peek("Tus-Resumable")) != tusVersion {
+		ctx.Response.Header.Set("Tus-Version", tusVersion)
+		respJSON.WriteJSONError(ctx, fasthttp.StatusPreconditionFailed, nil, "Unsupported tus version")
+		return
+	}
+
+	uploadID := strings.Trim(remainingPath, "/")
+	method := string(ctx.Method())
+
+	switch {
+	case uploadID == "" && method == fasthttp.MethodPost:
+		handleTusCreate(ctx)
+	case uploadID != "" && method == fasthttp.MethodHead:
+		handleTusHead(ctx, uploadID)
+	case uploadID != "" && method == fasthttp.MethodPatch:
+		handleTusPatch(ctx, uploadID)
+	case uploadID != "" && method == fasthttp.MethodDelete:
+		handleTusDelete(ctx, uploadID)
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+	}
+}
+
+func handleTusCreate(ctx *fasthttp.RequestCtx) {
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	length, err := strconv.ParseInt(string(ctx.Request.Header.Peek("Upload-Length")), 10, 64)
+	if err != nil || length < 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid Upload-Length")
+		return
+	}
+
+	metadata, err := parseTusMetadata(string(ctx.Request.Header.Peek("Upload-Metadata")))
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid Upload-Metadata")
+		return
+	}
+	filename := metadata["filename"]
+	if filename == "" {
+		filename = metadata["name"]
+	}
+	filename = filepath.Base(filename)
+	if filename == "" || filename == "." || filename == "/" {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, nil, "Missing filename in Upload-Metadata")
+		return
+	}
+
+	isStream := false
+	if isStreamParam, ok := metadata["is_stream"]; ok {
+		if isStream, err = parseIsStream(isStreamParam); err != nil {
+			respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, nil, "Invalid value for is_stream. Expecting true/false or 1/0")
+			return
+		}
+	}
+
+	upload, err := service.CreateUpload(userID, filename, length, isStream)
+	if err != nil {
+		writeTusError(ctx, err, "Failed to create upload")
+		return
+	}
+
+	if string(ctx.Request.Header.ContentType()) == "application/offset+octet-stream" {
+		uploadID := upload.Id
+		upload, err = service.WriteUploadChunk(uploadID, userID, 0, requestBodyReader(ctx))
+		if err != nil {
+			// The client never learns the upload's location, so it cannot resume it either.
+			if termErr := service.TerminateUpload(uploadID, userID); termErr != nil && !errors.Is(termErr, mysql.ErrUploadNotFound) {
+				logrus.Errorf("failed to terminate upload %s: %v", uploadID, termErr)
+			}
+			writeTusError(ctx, err, "Failed to write upload chunk")
+			return
+		}
+		ctx.Response.Header.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	}
+
+	ctx.Response.Header.Set("Location", fmt.Sprintf("%s/%s", tusBasePath, upload.Id))
+	setUploadExpires(ctx, upload)
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+}
+
+func handleTusHead(ctx *fasthttp.RequestCtx, uploadID string) {
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	upload, err := service.GetUpload(uploadID, userID)
+	if err != nil {
+		writeTusError(ctx, err, "Failed to get upload")
+		return
+	}
+
+	ctx.Response.Header.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.Response.Header.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+	setUploadExpires(ctx, upload)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+func handleTusPatch(ctx *fasthttp.RequestCtx, uploadID string) {
+	if string(ctx.Request.Header.ContentType()) != "application/offset+octet-stream" {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnsupportedMediaType, nil, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+
+	offset, err := strconv.ParseInt(string(ctx.Request.Header.Peek("Upload-Offset")), 10, 64)
+	if err != nil || offset < 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid Upload-Offset")
+		return
+	}
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	upload, err := service.WriteUploadChunk(uploadID, userID, offset, requestBodyReader(ctx))
+	if err != nil {
+		writeTusError(ctx, err, "Failed to write upload chunk")
+		return
+	}
+
+	ctx.Response.Header.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	setUploadExpires(ctx, upload)
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func handleTusDelete(ctx *fasthttp.RequestCtx, uploadID string) {
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	if err = service.TerminateUpload(uploadID, userID); err != nil {
+		writeTusError(ctx, err, "Failed to terminate upload")
+		return
+	}
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// setUploadExpires advertises when an unfinished upload is removed.
+func setUploadExpires(ctx *fasthttp.RequestCtx, upload *models.Upload) {
+	if upload.FileId == 0 {
+		ctx.Response.Header.Set("Upload-Expires", service.UploadExpiresAt(upload).UTC().Format(http.TimeFormat))
+	}
+}
+
+func writeTusError(ctx *fasthttp.RequestCtx, err error, message string) {
+	switch {
+	case errors.Is(err, mysql.ErrUploadNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, message)
+	case errors.Is(err, service.ErrUploadOffset), errors.Is(err, service.ErrUploadComplete), errors.Is(err, mysql.ErrDuplicateFile):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusConflict, err, message)
+	case errors.Is(err, service.ErrUploadTooLarge):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusRequestEntityTooLarge, err, message)
+	case errors.Is(err, service.ErrUnsupportedFormat):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnsupportedMediaType, err, message)
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, message)
+	}
+}
+
+func requestBodyReader(ctx *fasthttp.RequestCtx) io.Reader {
+	if stream := ctx.RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(ctx.PostBody())
+}
+
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if header == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if parts[0] == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		if len(parts) == 1 {
+			metadata[parts[0]] = ""
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for key %s: %w", parts[0], err)
+		}
+		metadata[parts[0]] = string(value)
+	}
+	return metadata, nil
+}
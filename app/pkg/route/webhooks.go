@@ -0,0 +1,137 @@
+package route
+
+import (
+	"errors"
+	"github.com/Dimoonevs/video-service/app/internal/models"
+	"github.com/Dimoonevs/video-service/app/internal/repo/mysql"
+	"github.com/Dimoonevs/video-service/app/internal/service"
+	"github.com/Dimoonevs/video-service/app/pkg/respJSON"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+func handleWebhookRoutes(ctx *fasthttp.RequestCtx, remainingPath string) {
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err, "Error getting user id: ")
+		return
+	}
+
+	if remainingPath == "" || remainingPath == "/" {
+		switch {
+		case ctx.IsGet():
+			handleWebhookList(ctx, userID)
+		case ctx.IsPost():
+			handleWebhookCreate(ctx, userID)
+		default:
+			respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+		}
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(remainingPath, "/"), "/")
+	webhookID, err := strconv.Atoi(idStr)
+	if err != nil || webhookID <= 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	switch {
+	case action == "" && ctx.IsGet():
+		hook, err := service.GetWebhook(webhookID, userID)
+		if err != nil {
+			writeWebhookError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Webhook retrieved successfully", hook)
+	case action == "" && string(ctx.Method()) == fasthttp.MethodPatch:
+		handleWebhookUpdate(ctx, webhookID, userID)
+	case action == "" && string(ctx.Method()) == fasthttp.MethodDelete:
+		if err = service.DeleteWebhook(webhookID, userID); err != nil {
+			writeWebhookError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Webhook deleted successfully", nil)
+	case action == "deliveries" && ctx.IsGet():
+		deliveries, err := service.GetWebhookDeliveries(webhookID, userID, ctx.QueryArgs().GetUintOrZero("limit"))
+		if err != nil {
+			writeWebhookError(ctx, err)
+			return
+		}
+		respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Webhook deliveries retrieved successfully", deliveries)
+	case strings.HasPrefix(action, "deliveries/") && strings.HasSuffix(action, "/redeliver") && ctx.IsPost():
+		handleWebhookRedeliver(ctx, webhookID, userID, strings.TrimSuffix(strings.TrimPrefix(action, "deliveries/"), "/redeliver"))
+	case action == "" || action == "deliveries":
+		respJSON.WriteJSONError(ctx, fasthttp.StatusMethodNotAllowed, nil, "Method not allowed")
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+	}
+}
+
+func handleWebhookList(ctx *fasthttp.RequestCtx, userID int) {
+	hooks, err := service.GetWebhooks(userID)
+	if err != nil {
+		writeWebhookError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Webhooks retrieved successfully", hooks)
+}
+
+func handleWebhookCreate(ctx *fasthttp.RequestCtx, userID int) {
+	var req models.WebhookReq
+	if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+		return
+	}
+
+	hook, err := service.CreateWebhook(userID, &req)
+	if err != nil {
+		writeWebhookError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusCreated, "Webhook created successfully", hook)
+}
+
+func handleWebhookUpdate(ctx *fasthttp.RequestCtx, webhookID, userID int) {
+	var req models.WebhookReq
+	if !decodeJSONBody(ctx, &req, maxJSONBodySize) {
+		return
+	}
+
+	hook, err := service.UpdateWebhook(webhookID, userID, &req)
+	if err != nil {
+		writeWebhookError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusOK, "Webhook updated successfully", hook)
+}
+
+func handleWebhookRedeliver(ctx *fasthttp.RequestCtx, webhookID, userID int, deliveryIDStr string) {
+	deliveryID, err := strconv.ParseInt(deliveryIDStr, 10, 64)
+	if err != nil || deliveryID <= 0 {
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, nil, "Endpoint not found")
+		return
+	}
+
+	newID, err := service.RedeliverWebhook(webhookID, userID, deliveryID)
+	if err != nil {
+		writeWebhookError(ctx, err)
+		return
+	}
+	respJSON.WriteJSONResponse(ctx, fasthttp.StatusAccepted, "Webhook delivery queued", map[string]int64{"delivery_id": newID})
+}
+
+func writeWebhookError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhook):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusBadRequest, err, "Invalid webhook")
+	case errors.Is(err, service.ErrWebhookLimit):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusConflict, err, "Webhook limit reached")
+	case errors.Is(err, mysql.ErrWebhookNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Webhook not found")
+	case errors.Is(err, mysql.ErrDeliveryNotFound):
+		respJSON.WriteJSONError(ctx, fasthttp.StatusNotFound, err, "Webhook delivery not found")
+	default:
+		respJSON.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err, "Failed to process webhook")
+	}
+}
@@ -0,0 +1,117 @@
+package signedurl
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var (
+	oldKey = key{id: "2024", secret: []byte("old-secret")}
+	newKey = key{id: "2025", secret: []byte("new-secret")}
+)
+
+// setKeys replaces the configured keys for one test; the first one signs.
+func setKeys(t *testing.T, active ...key) {
+	t.Helper()
+	keysOnce.Do(func() {})
+	saved := keys
+	keys = active
+	t.Cleanup(func() { keys = saved })
+}
+
+func setTTL(t *testing.T, d time.Duration) {
+	t.Helper()
+	saved := *ttl
+	*ttl = d
+	t.Cleanup(func() { *ttl = saved })
+}
+
+func setBindIP(t *testing.T, bind bool) {
+	t.Helper()
+	saved := *bindIP
+	*bindIP = bind
+	t.Cleanup(func() { *bindIP = saved })
+}
+
+func parseSigned(t *testing.T, signed string) Params {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("signed URL %q does not parse: %v", signed, err)
+	}
+	query := u.Query()
+	expires, err := strconv.ParseInt(query.Get("exp"), 10, 64)
+	if err != nil {
+		t.Fatalf("signed URL %q has no valid exp: %v", signed, err)
+	}
+	return Params{
+		Expires:   expires,
+		KeyID:     query.Get("kid"),
+		BindIP:    query.Get("ip") == "1",
+		Signature: query.Get("sig"),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		signKeys  []key
+		rotate    []key
+		ttl       time.Duration
+		bindIP    bool
+		signIP    string
+		verifyIP  string
+		rendition string
+		keyID     string
+		want      error
+	}{
+		{name: "valid", signKeys: []key{newKey}, want: nil},
+		{name: "expired", signKeys: []key{newKey}, ttl: -time.Minute, want: ErrExpired},
+		{name: "bound to client IP", signKeys: []key{newKey}, bindIP: true, signIP: "203.0.113.7", verifyIP: "203.0.113.7", want: nil},
+		{name: "wrong IP", signKeys: []key{newKey}, bindIP: true, signIP: "203.0.113.7", verifyIP: "198.51.100.2", want: ErrInvalidSignature},
+		{name: "IP ignored when not bound", signKeys: []key{newKey}, signIP: "203.0.113.7", verifyIP: "198.51.100.2", want: nil},
+		{name: "other rendition", signKeys: []key{newKey}, rendition: "1920x1080", want: ErrInvalidSignature},
+		{name: "unknown kid", signKeys: []key{newKey}, keyID: "1999", want: ErrInvalidSignature},
+		{name: "old kid verifies during rotation", signKeys: []key{oldKey}, rotate: []key{newKey, oldKey}, want: nil},
+		{name: "old kid rejected once retired", signKeys: []key{oldKey}, rotate: []key{newKey}, want: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttlValue := tt.ttl
+			if ttlValue == 0 {
+				ttlValue = time.Hour
+			}
+			setTTL(t, ttlValue)
+			setBindIP(t, tt.bindIP)
+			setKeys(t, tt.signKeys...)
+
+			params := parseSigned(t, Sign(42, "1280x720", tt.signIP))
+			if tt.rotate != nil {
+				keys = tt.rotate
+			}
+			if tt.keyID != "" {
+				params.KeyID = tt.keyID
+			}
+			rendition := "1280x720"
+			if tt.rendition != "" {
+				rendition = tt.rendition
+			}
+
+			if err := Verify(42, rendition, params, tt.verifyIP); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignUsesFirstKey(t *testing.T) {
+	setKeys(t, newKey, oldKey)
+
+	if params := parseSigned(t, Sign(7, "source", "")); params.KeyID != newKey.id {
+		t.Errorf("Sign() used kid %q, want %q", params.KeyID, newKey.id)
+	}
+}
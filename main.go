@@ -9,15 +9,20 @@ import (
 )
 
 var (
-	port = flag.String("port", "8080", "Port to listen on")
+	port               = flag.String("port", "8080", "Port to listen on")
+	maxRequestBodySize = flag.Int("maxRequestBodySize", 4*1024*1024, "Request body size buffered in memory before streaming")
 )
 
 func main() {
 	iniflags.Parse()
+	route.StartBackgroundTasks()
 
 	server := &fasthttp.Server{
 		Handler:            route.RequestHandler,
-		MaxRequestBodySize: 20 * 104 * 1024 * 1024,
+		MaxRequestBodySize: *maxRequestBodySize,
+		StreamRequestBody:  true,
+		// Multipart uploads are parsed by the handler once the request is authenticated and within size.
+		DisablePreParseMultipartForm: true,
 	}
 
 	fmt.Printf("Server is running on %s...\n", *port)
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    id            VARCHAR(32)   NOT NULL PRIMARY KEY,
+    user_id       INT           NOT NULL,
+    filename      VARCHAR(255)  NOT NULL,
+    filepath      VARCHAR(1024) NOT NULL,
+    is_stream     TINYINT(1)    NOT NULL DEFAULT 0,
+    upload_length BIGINT        NOT NULL,
+    upload_offset BIGINT        NOT NULL DEFAULT 0,
+    file_id       INT           NULL,
+    created_at    TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_uploads_user_id (user_id)
+);
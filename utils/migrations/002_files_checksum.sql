@@ -0,0 +1,3 @@
+ALTER TABLE files
+    ADD COLUMN size     BIGINT   NULL,
+    ADD COLUMN checksum CHAR(64) NULL;
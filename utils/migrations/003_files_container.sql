@@ -0,0 +1,3 @@
+ALTER TABLE files
+    ADD COLUMN container VARCHAR(16) NULL,
+    ADD COLUMN brand     VARCHAR(32) NULL;
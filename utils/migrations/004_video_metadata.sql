@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS video_metadata (
+    file_id     INT          NOT NULL PRIMARY KEY,
+    duration_ms BIGINT       NOT NULL DEFAULT 0,
+    width       INT          NOT NULL DEFAULT 0,
+    height      INT          NOT NULL DEFAULT 0,
+    frame_rate  DOUBLE       NOT NULL DEFAULT 0,
+    video_codec VARCHAR(16)  NOT NULL DEFAULT '',
+    audio_codec VARCHAR(16)  NOT NULL DEFAULT '',
+    bitrate     BIGINT       NOT NULL DEFAULT 0,
+    rotation    SMALLINT     NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_video_metadata_file FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE
+);
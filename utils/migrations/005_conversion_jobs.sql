@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS conversion_jobs (
+    id               BIGINT        NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    file_id          INT           NOT NULL,
+    status           ENUM('queued', 'leased', 'done', 'failed') NOT NULL DEFAULT 'queued',
+    attempts         INT           NOT NULL DEFAULT 0,
+    max_attempts     INT           NOT NULL DEFAULT 5,
+    available_at     DATETIME(3)   NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    worker_id        VARCHAR(64)   NULL,
+    lease_token      CHAR(32)      NULL,
+    lease_expires_at DATETIME(3)   NULL,
+    last_error       TEXT          NULL,
+    created_at       TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_conversion_jobs_file (file_id),
+    INDEX idx_conversion_jobs_queue (status, available_at),
+    INDEX idx_conversion_jobs_lease (status, lease_expires_at),
+    CONSTRAINT fk_conversion_jobs_file FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS file_status_history (
+    id         BIGINT        NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    file_id    INT           NOT NULL,
+    old_status VARCHAR(32)   NULL,
+    new_status VARCHAR(32)   NOT NULL,
+    actor      VARCHAR(96)   NOT NULL,
+    reason     TEXT          NULL,
+    created_at DATETIME(3)   NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_file_status_history_file (file_id, id),
+    CONSTRAINT fk_file_status_history_file FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+ALTER TABLE files
+    ADD COLUMN last_error          TEXT        NULL,
+    ADD COLUMN conversion_attempts INT         NOT NULL DEFAULT 0,
+    ADD COLUMN retry_count         INT         NOT NULL DEFAULT 0,
+    ADD COLUMN last_failed_at      DATETIME(3) NULL;
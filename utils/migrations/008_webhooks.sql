@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         INT           NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id    INT           NOT NULL,
+    url        VARCHAR(2048) NOT NULL,
+    secret     VARCHAR(64)   NOT NULL,
+    events     VARCHAR(255)  NOT NULL DEFAULT '',
+    active     TINYINT(1)    NOT NULL DEFAULT 1,
+    created_at TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_webhooks_user_id (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    webhook_id      INT          NOT NULL,
+    event           VARCHAR(64)  NOT NULL,
+    file_id         INT          NOT NULL,
+    payload         MEDIUMTEXT   NOT NULL,
+    status          ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    attempts        INT          NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    response_status INT          NULL,
+    last_error      TEXT         NULL,
+    created_at      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    delivered_at    DATETIME(3)  NULL,
+    INDEX idx_webhook_deliveries_queue (status, next_attempt_at),
+    INDEX idx_webhook_deliveries_webhook (webhook_id, id),
+    CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
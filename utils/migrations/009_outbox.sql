@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    aggregate_type  VARCHAR(32)  NOT NULL,
+    aggregate_id    INT          NOT NULL,
+    event_type      VARCHAR(64)  NOT NULL,
+    payload         MEDIUMTEXT   NOT NULL,
+    created_at      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    attempts        INT          NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    last_error      TEXT         NULL,
+    published_at    DATETIME(3)  NULL,
+    INDEX idx_outbox_events_pending (published_at, next_attempt_at)
+);
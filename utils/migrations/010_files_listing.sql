@@ -0,0 +1,16 @@
+ALTER TABLE files
+    ADD COLUMN created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    ADD COLUMN updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    ADD INDEX idx_files_user_created (user_id, created_at, id),
+    ADD INDEX idx_files_user_updated (user_id, updated_at, id),
+    ADD INDEX idx_files_user_filename (user_id, filename, id),
+    ADD INDEX idx_files_user_size (user_id, size, id);
+
+-- Existing files take their timestamps from the status history where there is one.
+UPDATE files f
+INNER JOIN (
+    SELECT file_id, MIN(created_at) AS first_change, MAX(created_at) AS last_change
+    FROM file_status_history
+    GROUP BY file_id
+) h ON h.file_id = f.id
+SET f.created_at = h.first_change, f.updated_at = h.last_change;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS video_transcripts (
+    file_id    INT          NOT NULL PRIMARY KEY,
+    language   VARCHAR(16)  NULL,
+    text       MEDIUMTEXT   NOT NULL,
+    updated_at DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    CONSTRAINT fk_video_transcripts_file FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE
+);
+
+-- video_search is the document the MySQL search index reads, rebuilt from files and video_transcripts
+-- whenever one of the indexed fields changes.
+CREATE TABLE IF NOT EXISTS video_search (
+    file_id     INT           NOT NULL PRIMARY KEY,
+    user_id     INT           NOT NULL,
+    filename    VARCHAR(255)  NOT NULL,
+    title       VARCHAR(255)  NOT NULL DEFAULT '',
+    description TEXT          NOT NULL,
+    tags        TEXT          NOT NULL,
+    transcript  MEDIUMTEXT    NOT NULL,
+    INDEX idx_video_search_user (user_id),
+    FULLTEXT INDEX ft_video_search_all (filename, title, description, tags, transcript),
+    FULLTEXT INDEX ft_video_search_name (filename, title),
+    CONSTRAINT fk_video_search_file FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE
+);
+
+INSERT INTO video_search (file_id, user_id, filename, description, tags, transcript)
+SELECT id, user_id, filename, '', '', ''
+FROM files
+WHERE status <> 'deleted'
+ON DUPLICATE KEY UPDATE filename = VALUES(filename);
@@ -0,0 +1,6 @@
+ALTER TABLE files
+    ADD COLUMN title         VARCHAR(255)  NULL,
+    ADD COLUMN description   TEXT          NULL,
+    ADD COLUMN tags          VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD COLUMN language      VARCHAR(16)   NULL,
+    ADD COLUMN custom_fields JSON          NULL;
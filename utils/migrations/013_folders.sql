@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS folders (
+    id         INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id    INT          NOT NULL,
+    parent_id  INT          NULL,
+    name       VARCHAR(255) NOT NULL,
+    -- Root folders have no parent; parent_key lets the unique key cover them too. MySQL refuses
+    -- ON DELETE CASCADE on the base column of a stored generated column, so subfolders are deleted
+    -- by the application, deepest first.
+    parent_key INT          AS (COALESCE(parent_id, 0)) STORED,
+    created_at DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    UNIQUE KEY uq_folders_sibling_name (user_id, parent_key, name),
+    INDEX idx_folders_parent (parent_id),
+    CONSTRAINT fk_folders_parent FOREIGN KEY (parent_id) REFERENCES folders (id) ON DELETE RESTRICT
+);
+
+ALTER TABLE files
+    ADD COLUMN folder_id INT NULL,
+    ADD INDEX idx_files_user_folder (user_id, folder_id),
+    ADD CONSTRAINT fk_files_folder FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE SET NULL;
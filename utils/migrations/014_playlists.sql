@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS playlists (
+    id          INT           NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id     INT           NOT NULL,
+    title       VARCHAR(255)  NOT NULL,
+    description TEXT          NULL,
+    visibility  ENUM('private', 'unlisted', 'public') NOT NULL DEFAULT 'private',
+    share_token CHAR(32)      NOT NULL,
+    created_at  DATETIME(3)   NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at  DATETIME(3)   NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    UNIQUE KEY uq_playlists_share_token (share_token),
+    INDEX idx_playlists_user_id (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS playlist_items (
+    id          BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    playlist_id INT         NOT NULL,
+    file_id     INT         NOT NULL,
+    position    INT         NOT NULL,
+    created_at  DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    UNIQUE KEY uq_playlist_items_file (playlist_id, file_id),
+    INDEX idx_playlist_items_position (playlist_id, position),
+    CONSTRAINT fk_playlist_items_playlist FOREIGN KEY (playlist_id) REFERENCES playlists (id) ON DELETE CASCADE,
+    CONSTRAINT fk_playlist_items_file FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE
+);